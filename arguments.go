@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Describes a positional argument declared by Arguments()
+type argumentSpec struct {
+	name     string
+	kind     string
+	required bool
+	variadic bool
+}
+
+// Types of positional arguments and descriptions used in errors
+var argumentKinds = map[string]string{
+	"string":   "a string",
+	"int":      "an integer",
+	"int64":    "a 64 bit integer",
+	"uint":     "an unsigned integer",
+	"float":    "a number",
+	"bool":     "true or false",
+	"duration": "a duration, e.g. 1m30s",
+	"bytesize": "a byte size, e.g. 512MB",
+	"percent":  "a percentage, e.g. 75%",
+}
+
+var argumentSpecRegexp = regexp.MustCompile(`^(<([\w-]+)(?::(\w+))?(\.\.\.)?>|\[([\w-]+)(?::(\w+))?(\.\.\.)?\])$`)
+
+// Declares positional arguments of the most recently specified command, e.g. "<src> <dst> [files...]",
+// <name> is required, [name] is optional, a trailing ... on the last argument takes all remaining arguments
+// and a type can be added after a colon, e.g. <count:int>. Supported types are string, int, int64, uint,
+// float, bool, duration, bytesize and percent. Arguments are shown in usage and checked before the callback
+func (b *Builder) Arguments(spec string) *Builder {
+	if b.command == nil || b.command.Action == nil {
+		panic("arguments must be specified after a command with a callback")
+	}
+	specs := parseArgumentSpecs(spec)
+	command := b.command
+	var usage []string
+	for _, spec := range specs {
+		usage = append(usage, spec.String())
+	}
+	command.ArgsUsage = strings.Join(usage, " ")
+	b.addCheck(command, func(cc *cli.Context) error {
+		if err := checkArguments(specs, cc.Args().Slice()); err != nil {
+			return fmt.Errorf("%s expects %s: %s", cc.Command.HelpName, command.ArgsUsage, err)
+		}
+		return nil
+	})
+	return b
+}
+
+func parseArgumentSpecs(spec string) (specs []argumentSpec) {
+	for _, field := range strings.Fields(spec) {
+		match := argumentSpecRegexp.FindStringSubmatch(field)
+		if match == nil {
+			panic(fmt.Sprintf("invalid argument %s, expected <name>, [name] or [name...] with an optional :type", field))
+		}
+		argument := argumentSpec{name: match[2], kind: match[3], required: true, variadic: len(match[4]) > 0}
+		if len(match[5]) > 0 {
+			argument = argumentSpec{name: match[5], kind: match[6], variadic: len(match[7]) > 0}
+		}
+		if len(argument.kind) == 0 {
+			argument.kind = "string"
+		}
+		if _, ok := argumentKinds[argument.kind]; !ok {
+			panic(fmt.Sprintf("unsupported type %s of argument %s", argument.kind, argument.name))
+		}
+		if len(specs) > 0 {
+			previous := specs[len(specs)-1]
+			if previous.variadic {
+				panic(fmt.Sprintf("argument %s follows variadic argument %s", argument.name, previous.name))
+			} else if argument.required && !previous.required {
+				panic(fmt.Sprintf("required argument %s follows optional argument %s", argument.name, previous.name))
+			}
+		}
+		specs = append(specs, argument)
+	}
+	return specs
+}
+
+func (s argumentSpec) String() string {
+	name := s.name
+	if s.variadic {
+		name += "..."
+	}
+	if s.required {
+		return "<" + name + ">"
+	}
+	return "[" + name + "]"
+}
+
+// Checks count and types of args against the specs
+func checkArguments(specs []argumentSpec, args []string) error {
+	for i, spec := range specs {
+		if i >= len(args) {
+			if spec.required {
+				return fmt.Errorf("missing argument %s", spec.name)
+			}
+			return nil
+		}
+		values := args[i : i+1]
+		if spec.variadic {
+			values = args[i:]
+		}
+		for _, value := range values {
+			if _, err := parseArgument(spec.kind, value); err != nil {
+				return fmt.Errorf("argument %s expects %s, got %q", spec.name, argumentKinds[spec.kind], value)
+			}
+		}
+	}
+	if len(args) > len(specs) && (len(specs) == 0 || !specs[len(specs)-1].variadic) {
+		return fmt.Errorf("unexpected argument %s", args[len(specs)])
+	}
+	return nil
+}
+
+func parseArgument(kind string, raw string) (interface{}, error) {
+	switch kind {
+	case "int":
+		return strconv.Atoi(raw)
+	case "int64":
+		return strconv.ParseInt(raw, 10, 64)
+	case "uint":
+		v, err := strconv.ParseUint(raw, 10, 0)
+		return uint(v), err
+	case "float":
+		return strconv.ParseFloat(raw, 64)
+	case "bool":
+		return strconv.ParseBool(raw)
+	case "duration":
+		return time.ParseDuration(raw)
+	case "bytesize":
+		return ParseByteSize(raw)
+	case "percent":
+		return ParsePercent(raw)
+	}
+	return raw, nil
+}
+
+// Returns argument at index, also returns "" if argument is missing
+func (a Args) String(index int) string {
+	val, _ := a.LookupString(index)
+	return val
+}
+
+// Returns integer argument at index, also returns 0 if argument is missing or invalid
+func (a Args) Integer(index int) int {
+	val, _ := a.LookupInteger(index)
+	return val
+}
+
+// Returns 64 bit integer argument at index, also returns 0 if argument is missing or invalid
+func (a Args) Int64(index int) int64 {
+	val, _ := a.LookupInt64(index)
+	return val
+}
+
+// Returns unsigned integer argument at index, also returns 0 if argument is missing or invalid
+func (a Args) Uint(index int) uint {
+	val, _ := a.LookupUint(index)
+	return val
+}
+
+// Returns float argument at index, also returns 0 if argument is missing or invalid
+func (a Args) Float64(index int) float64 {
+	val, _ := a.LookupFloat64(index)
+	return val
+}
+
+// Returns boolean argument at index, also returns false if argument is missing or invalid
+func (a Args) Boolean(index int) bool {
+	val, _ := a.LookupBoolean(index)
+	return val
+}
+
+// Returns duration argument at index, also returns 0 if argument is missing or invalid
+func (a Args) Duration(index int) time.Duration {
+	val, _ := a.LookupDuration(index)
+	return val
+}
+
+// Returns byte size argument at index, also returns 0 if argument is missing or invalid
+func (a Args) ByteSize(index int) ByteSize {
+	val, _ := a.LookupByteSize(index)
+	return val
+}
+
+// Returns percentage argument at index, also returns 0 if argument is missing or invalid
+func (a Args) Percent(index int) Percent {
+	val, _ := a.LookupPercent(index)
+	return val
+}
+
+// Returns arguments from index on, e.g. those taken by a variadic argument
+func (a Args) Rest(index int) []string {
+	if index >= len(a) {
+		return nil
+	}
+	return a[index:]
+}
+
+// Returns argument at index, fails if argument is missing
+func (a Args) LookupString(index int) (string, error) {
+	if index < 0 || index >= len(a) {
+		return "", fmt.Errorf("argument %d is missing", index)
+	}
+	return a[index], nil
+}
+
+// Returns integer argument at index, fails if argument is missing or invalid
+func (a Args) LookupInteger(index int) (int, error) {
+	val, err := a.lookup(index, "int")
+	v, _ := val.(int)
+	return v, err
+}
+
+// Returns 64 bit integer argument at index, fails if argument is missing or invalid
+func (a Args) LookupInt64(index int) (int64, error) {
+	val, err := a.lookup(index, "int64")
+	v, _ := val.(int64)
+	return v, err
+}
+
+// Returns unsigned integer argument at index, fails if argument is missing or invalid
+func (a Args) LookupUint(index int) (uint, error) {
+	val, err := a.lookup(index, "uint")
+	v, _ := val.(uint)
+	return v, err
+}
+
+// Returns float argument at index, fails if argument is missing or invalid
+func (a Args) LookupFloat64(index int) (float64, error) {
+	val, err := a.lookup(index, "float")
+	v, _ := val.(float64)
+	return v, err
+}
+
+// Returns boolean argument at index, fails if argument is missing or invalid
+func (a Args) LookupBoolean(index int) (bool, error) {
+	val, err := a.lookup(index, "bool")
+	v, _ := val.(bool)
+	return v, err
+}
+
+// Returns duration argument at index, fails if argument is missing or invalid
+func (a Args) LookupDuration(index int) (time.Duration, error) {
+	val, err := a.lookup(index, "duration")
+	v, _ := val.(time.Duration)
+	return v, err
+}
+
+// Returns byte size argument at index, fails if argument is missing or invalid
+func (a Args) LookupByteSize(index int) (ByteSize, error) {
+	val, err := a.lookup(index, "bytesize")
+	v, _ := val.(ByteSize)
+	return v, err
+}
+
+// Returns percentage argument at index, fails if argument is missing or invalid
+func (a Args) LookupPercent(index int) (Percent, error) {
+	val, err := a.lookup(index, "percent")
+	v, _ := val.(Percent)
+	return v, err
+}
+
+func (a Args) lookup(index int, kind string) (interface{}, error) {
+	raw, err := a.LookupString(index)
+	if err != nil {
+		return nil, err
+	}
+	val, err := parseArgument(kind, raw)
+	if err != nil {
+		return nil, fmt.Errorf("argument %d expects %s, got %q", index, argumentKinds[kind], raw)
+	}
+	return val, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ake-persson/mapslice-json"
 	cli "github.com/urfave/cli/v2"
@@ -32,6 +33,18 @@ type Builder struct {
 	runner          *Runner
 	config          interface{}
 	configStructure mapslice.MapSlice
+	configFields    []*configField
+	configCommand   bool
+	builtinFlags    []cli.Flag
+	command         *cli.Command                      // Most recently specified command, target of command modifiers
+	constraints     map[*cli.Command][]flagConstraint // Global constraints are held by the nil command
+	persistent      map[string][]cli.Flag             // Persistent flags keyed by command, global ones by ""
+	notes           map[*cli.Command]*commandNotes
+	middleware      map[*cli.Command][]Middleware    // Global middleware is held by the nil command
+	after           map[*cli.Command][]AfterCallback // Global after hooks are held by the nil command
+	checks          map[*cli.Command][]commandCheck  // Checks of arguments and flags invoked before the callback
+	completion      bool
+	completers      map[completionTarget]Completer
 }
 
 // Parses args and runs cli application
@@ -49,15 +62,44 @@ func (b *Builder) Run(args ...string) (*Runner, error) {
 	signalHandler(b.runner.ctx, b.runner.cancelFunc)
 
 	b.preConfig()
+	b.inheritPersistentFlags()
+	b.commandHelp()
+	if err := b.checkFlagCollisions(); err != nil {
+		if b.preventMain {
+			b.runner.Exit(err)
+		}
+		return b.runner, err
+	}
+	if err := b.checkConstraintFlags(); err != nil {
+		if b.preventMain {
+			b.runner.Exit(err)
+		}
+		return b.runner, err
+	}
+	b.groupFlagHelp()
+
+	if b.completion && len(os.Args) > 1 && os.Args[1] == completeCommandName {
+		b.complete(os.Stdout, os.Args[2:])
+		b.runner.Exit(nil)
+	}
 
+	builtinCommandUsed := false // To prevent built in commands making app proceed
 	b.app.Before = func(c *cli.Context) error {
+		builtinCommandUsed = b.builtinCommandInvoked(c)
 		for _, flagName := range c.LocalFlagNames() {
 			b.runner.flags[flagName] = c.Value(flagName)
 		}
 
 		b.runner.args = Args(c.Args().Slice())
 
-		b.postConfig(c)
+		if err := b.postConfig(c); err != nil && !b.configRepairInvoked(c) && !(b.completion && c.Args().First() == "completion") {
+			return err
+		}
+		if !b.utilityCommandInvoked(c) {
+			if err := checkConstraints(c, b.constraints[nil]); err != nil {
+				return err
+			}
+		}
 		if b.before != nil {
 			return b.before(b.runner, b.runner.Args(), b.runner.Flags())
 		}
@@ -87,7 +129,7 @@ func (b *Builder) Run(args ...string) (*Runner, error) {
 	err := b.app.Run(os.Args)
 	if b.preventMain {
 		b.runner.Exit(err)
-	} else if helpFlagUsed || versionFlagUsed {
+	} else if helpFlagUsed || versionFlagUsed || (builtinCommandUsed && err == nil) {
 		b.runner.Exit(nil)
 	}
 
@@ -115,6 +157,54 @@ func (b *Builder) StringFlag(name string, usage string, aliases ...string) *Buil
 	return b
 }
 
+// Float64Flag specifices a float flag variable input by provided name, usage and aliases
+func (b *Builder) Float64Flag(name string, usage string, aliases ...string) *Builder {
+	b.app.Flags = append(b.app.Flags, Float64Flag(name, usage, aliases...))
+	return b
+}
+
+// Int64Flag specifices a 64 bit integer flag variable input by provided name, usage and aliases
+func (b *Builder) Int64Flag(name string, usage string, aliases ...string) *Builder {
+	b.app.Flags = append(b.app.Flags, Int64Flag(name, usage, aliases...))
+	return b
+}
+
+// UintFlag specifices an unsigned integer flag variable input by provided name, usage and aliases
+func (b *Builder) UintFlag(name string, usage string, aliases ...string) *Builder {
+	b.app.Flags = append(b.app.Flags, UintFlag(name, usage, aliases...))
+	return b
+}
+
+// DurationFlag specifices a duration flag variable, e.g. 1m30s, input by provided name, usage and aliases
+func (b *Builder) DurationFlag(name string, usage string, aliases ...string) *Builder {
+	b.app.Flags = append(b.app.Flags, DurationFlag(name, usage, aliases...))
+	return b
+}
+
+// StringSliceFlag specifices a string slice flag variable, which may be repeated, input by provided name, usage and aliases
+func (b *Builder) StringSliceFlag(name string, usage string, aliases ...string) *Builder {
+	b.app.Flags = append(b.app.Flags, StringSliceFlag(name, usage, aliases...))
+	return b
+}
+
+// IntSliceFlag specifices an integer slice flag variable, which may be repeated, input by provided name, usage and aliases
+func (b *Builder) IntSliceFlag(name string, usage string, aliases ...string) *Builder {
+	b.app.Flags = append(b.app.Flags, IntSliceFlag(name, usage, aliases...))
+	return b
+}
+
+// TimestampFlag specifices a timestamp flag variable parsed by layout, e.g. 2006-01-02, input by provided name, usage and aliases
+func (b *Builder) TimestampFlag(name string, usage string, layout string, aliases ...string) *Builder {
+	b.app.Flags = append(b.app.Flags, TimestampFlag(name, usage, layout, aliases...))
+	return b
+}
+
+// PathFlag specifices a file path flag variable input by provided name, usage and aliases
+func (b *Builder) PathFlag(name string, usage string, aliases ...string) *Builder {
+	b.app.Flags = append(b.app.Flags, PathFlag(name, usage, aliases...))
+	return b
+}
+
 // Makes application exit after Run() finishes instead of a normal return
 func (b *Builder) DisableMain() *Builder {
 	b.preventMain = true
@@ -127,61 +217,79 @@ func (b *Builder) Before(callback Callback) *Builder {
 	return b
 }
 
+// Specifies a command by name, or by a space separated path such as "db migrate up" to nest it
+// below parent commands, which are created if missing
 func (b *Builder) Command(name string, usage string, callback Callback, flags ...cli.Flag) *Builder {
-	b.app.Commands = append(b.app.Commands, &cli.Command{
-		Name:  name,
-		Usage: usage,
-		Action: func(cc *cli.Context) error {
-			b.runner.isMain = false
-			parsedFlags := make(Flags)
-			for _, flagName := range cc.LocalFlagNames() {
-				parsedFlags[flagName] = cc.Value(flagName)
-			}
-			parsedArgs := Args(cc.Args().Slice())
-			if err := callback(b.runner, parsedArgs, parsedFlags); err != nil {
-				return err
-			}
-			return nil
-		},
-		Flags: flags,
-	})
+	command := b.ensureCommand(name)
+	b.command = command
+	b.command.Usage = usage
+	b.command.Action = func(cc *cli.Context) error {
+		b.runner.isMain = false
+		return b.invoke(cc, command, callback)
+	}
+	b.command.Flags = append(b.command.Flags, flags...)
 	return b
 }
 
+// Specifies a command below parent, which may be a space separated path such as "db migrate"
 func (b *Builder) SubCommand(parent string, name string, usage string, callback Callback, flags ...cli.Flag) *Builder {
-	var selectedCommand *cli.Command
-	for _, command := range b.app.Commands {
-		if command.Name == parent {
-			selectedCommand = command
-			break
-		}
+	return b.Command(parent+" "+name, usage, callback, flags...)
+}
+
+// Specifies a command grouping sub commands, by name or space separated path, the flags of a group
+// are given before the name of a sub command
+func (b *Builder) Group(name string, usage string, flags ...cli.Flag) *Builder {
+	b.command = b.ensureCommand(name)
+	b.command.Usage = usage
+	b.command.Flags = append(b.command.Flags, flags...)
+	return b
+}
+
+// Sets callback to be invoked before the most recently specified command or group, a group invokes it
+// before any of its sub commands
+func (b *Builder) CommandBefore(callback Callback) *Builder {
+	if b.command == nil {
+		panic("command before must be specified after a command")
 	}
-	if selectedCommand == nil {
-		selectedCommand = &cli.Command{
-			Name: parent,
-		}
-		b.app.Commands = append(b.app.Commands, selectedCommand)
-	}
-	selectedCommand.Subcommands = append(selectedCommand.Subcommands, &cli.Command{
-		Name:  name,
-		Usage: usage,
-		Action: func(cc *cli.Context) error {
-			b.runner.isMain = false
-			parsedFlags := make(Flags)
-			for _, flagName := range cc.LocalFlagNames() {
-				parsedFlags[flagName] = cc.Value(flagName)
-			}
-			parsedArgs := Args(cc.Args().Slice())
-			if err := callback(b.runner, parsedArgs, parsedFlags); err != nil {
+	previous := b.command.Before
+	b.command.Before = func(cc *cli.Context) error {
+		if previous != nil {
+			if err := previous(cc); err != nil {
 				return err
 			}
-			return nil
-		},
-		Flags: flags,
-	})
+		}
+		return callback(b.runner, Args(cc.Args().Slice()), mergedFlags(cc))
+	}
 	return b
 }
 
+// Returns the command by space separated path, creating it and its parents if missing
+func (b *Builder) ensureCommand(path string) *cli.Command {
+	names := strings.Fields(path)
+	if len(names) == 0 {
+		panic("command name is empty")
+	}
+	var command *cli.Command
+	commands := &b.app.Commands
+	for _, name := range names {
+		command = nil
+		for _, existing := range *commands {
+			if existing.HasName(name) {
+				command = existing
+				break
+			}
+		}
+		if command == nil {
+			command = &cli.Command{
+				Name: name,
+			}
+			*commands = append(*commands, command)
+		}
+		commands = &command.Subcommands
+	}
+	return command
+}
+
 func (b *Builder) Config(config interface{}) *Builder {
 	b.config = config
 	return b
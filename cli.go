@@ -54,3 +54,76 @@ func StringFlag(name string, usage string, aliases ...string) cli.Flag {
 		Aliases: aliases,
 	}
 }
+
+// Float64Flag specifices a float flag variable input by provided name, usage and aliases
+func Float64Flag(name string, usage string, aliases ...string) cli.Flag {
+	return &cli.Float64Flag{
+		Name:    name,
+		Usage:   usage,
+		Aliases: aliases,
+	}
+}
+
+// Int64Flag specifices a 64 bit integer flag variable input by provided name, usage and aliases
+func Int64Flag(name string, usage string, aliases ...string) cli.Flag {
+	return &cli.Int64Flag{
+		Name:    name,
+		Usage:   usage,
+		Aliases: aliases,
+	}
+}
+
+// UintFlag specifices an unsigned integer flag variable input by provided name, usage and aliases
+func UintFlag(name string, usage string, aliases ...string) cli.Flag {
+	return &cli.UintFlag{
+		Name:    name,
+		Usage:   usage,
+		Aliases: aliases,
+	}
+}
+
+// DurationFlag specifices a duration flag variable, e.g. 1m30s, input by provided name, usage and aliases
+func DurationFlag(name string, usage string, aliases ...string) cli.Flag {
+	return &cli.DurationFlag{
+		Name:    name,
+		Usage:   usage,
+		Aliases: aliases,
+	}
+}
+
+// StringSliceFlag specifices a string slice flag variable, which may be repeated, input by provided name, usage and aliases
+func StringSliceFlag(name string, usage string, aliases ...string) cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:    name,
+		Usage:   usage,
+		Aliases: aliases,
+	}
+}
+
+// IntSliceFlag specifices an integer slice flag variable, which may be repeated, input by provided name, usage and aliases
+func IntSliceFlag(name string, usage string, aliases ...string) cli.Flag {
+	return &cli.IntSliceFlag{
+		Name:    name,
+		Usage:   usage,
+		Aliases: aliases,
+	}
+}
+
+// TimestampFlag specifices a timestamp flag variable parsed by layout, e.g. 2006-01-02, input by provided name, usage and aliases
+func TimestampFlag(name string, usage string, layout string, aliases ...string) cli.Flag {
+	return &cli.TimestampFlag{
+		Name:    name,
+		Usage:   usage,
+		Layout:  layout,
+		Aliases: aliases,
+	}
+}
+
+// PathFlag specifices a file path flag variable input by provided name, usage and aliases
+func PathFlag(name string, usage string, aliases ...string) cli.Flag {
+	return &cli.PathFlag{
+		Name:    name,
+		Usage:   usage,
+		Aliases: aliases,
+	}
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Detects flag names, aliases and env vars used more than once, globally and within each command
+func (b *Builder) checkFlagCollisions() error {
+	globalFlags := append([]cli.Flag{}, b.app.Flags...)
+	if !b.app.HideHelp {
+		globalFlags = append(globalFlags, cli.HelpFlag)
+	}
+	if !b.app.HideVersion && len(b.app.Version) > 0 {
+		globalFlags = append(globalFlags, cli.VersionFlag)
+	}
+	problems := b.flagCollisions(globalFlags, "")
+	var scanCommands func(commands []*cli.Command, path string)
+	scanCommands = func(commands []*cli.Command, path string) {
+		for _, command := range commands {
+			commandPath := strings.TrimSpace(path + " " + command.Name)
+			problems = append(problems, b.flagCollisions(command.Flags, fmt.Sprintf("command %s: ", commandPath))...)
+			scanCommands(command.Subcommands, commandPath)
+		}
+	}
+	scanCommands(b.app.Commands, "")
+	if len(problems) > 0 {
+		return fmt.Errorf("conflicting flags:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Lists names and env vars claimed by more than one of the flags
+func (b *Builder) flagCollisions(flags []cli.Flag, prefix string) (problems []string) {
+	var names, envs []string
+	nameOrigins := make(map[string][]string)
+	envOrigins := make(map[string][]string)
+	for _, flag := range flags {
+		origin := b.flagOrigin(flag)
+		for _, name := range flag.Names() {
+			if _, exists := nameOrigins[name]; !exists {
+				names = append(names, name)
+			}
+			nameOrigins[name] = append(nameOrigins[name], origin)
+		}
+		for _, env := range flagEnvVars(flag) {
+			if _, exists := envOrigins[env]; !exists {
+				envs = append(envs, env)
+			}
+			envOrigins[env] = appendUnique(envOrigins[env], origin)
+		}
+	}
+	for _, name := range names {
+		if len(nameOrigins[name]) > 1 {
+			problems = append(problems, fmt.Sprintf("%s%s%s is defined by %s", prefix, prefixFor(name), name, strings.Join(nameOrigins[name], " and ")))
+		}
+	}
+	for _, env := range envs {
+		if len(envOrigins[env]) > 1 {
+			problems = append(problems, fmt.Sprintf("%s$%s is read by %s", prefix, env, strings.Join(envOrigins[env], " and ")))
+		}
+	}
+	return problems
+}
+
+// Describes where a flag was declared, config fields are described by their struct path
+func (b *Builder) flagOrigin(flag cli.Flag) string {
+	for _, field := range b.configFields {
+		if field.flag == flag {
+			return "config field " + field.path
+		}
+	}
+	for _, builtin := range append(b.builtinFlags, cli.HelpFlag, cli.VersionFlag) {
+		if builtin == flag {
+			return "built-in flag --" + flag.Names()[0]
+		}
+	}
+	return "flag --" + flag.Names()[0]
+}
+
+// Returns the env vars of a flag, flags without an EnvVars field have none
+func flagEnvVars(flag cli.Flag) []string {
+	value := reflect.ValueOf(flag)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	field := value.FieldByName("EnvVars")
+	if !field.IsValid() {
+		return nil
+	}
+	envVars, _ := field.Interface().([]string)
+	return envVars
+}
+
+func prefixFor(name string) string {
+	if len(name) == 1 {
+		return "-"
+	}
+	return "--"
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, item := range list {
+		if item == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Returns the most recently specified command, target of command modifiers
+func (b *Builder) currentCommand(modifier string) *cli.Command {
+	if b.command == nil {
+		panic(fmt.Sprintf("%s must be specified after a command", modifier))
+	}
+	return b.command
+}
+
+// Ends the most recently specified command, so following modifiers such as Required() or CompleteFlag()
+// apply to global flags
+func (b *Builder) Global() *Builder {
+	b.command = nil
+	return b
+}
+
+// Adds aliases to the most recently specified command, e.g. ls for list
+func (b *Builder) Aliases(aliases ...string) *Builder {
+	command := b.currentCommand("aliases")
+	command.Aliases = append(command.Aliases, aliases...)
+	return b
+}
+
+// Lists the most recently specified command under a category heading in help
+func (b *Builder) Category(category string) *Builder {
+	b.currentCommand("category").Category = category
+	return b
+}
+
+// Leaves the most recently specified command out of help, e.g. internal or debug commands
+func (b *Builder) Hidden() *Builder {
+	b.currentCommand("hidden").Hidden = true
+	return b
+}
+
+// Marks the most recently specified command as deprecated, notice is shown in help
+// and printed as a warning whenever the command is used
+func (b *Builder) Deprecated(notice string) *Builder {
+	command := b.currentCommand("deprecated")
+	command.Usage = strings.TrimSpace(command.Usage + " (deprecated)")
+	b.commandNotes(command).deprecated = notice
+	before := command.Before
+	command.Before = func(cc *cli.Context) error {
+		fmt.Fprintf(os.Stderr, "Warning: command %s is deprecated: %s\n", command.Name, notice)
+		if before != nil {
+			return before(cc)
+		}
+		return nil
+	}
+	return b
+}
+
+// Sets the long description of the most recently specified command shown in its help
+func (b *Builder) Description(description string) *Builder {
+	b.currentCommand("description").Description = description
+	return b
+}
+
+// Adds usage examples, full command lines, shown in help of the most recently specified command
+func (b *Builder) Examples(examples ...string) *Builder {
+	notes := b.commandNotes(b.currentCommand("examples"))
+	notes.examples = append(notes.examples, examples...)
+	return b
+}
+
+// Help sections of a command not supported by cli.Command
+type commandNotes struct {
+	deprecated string
+	examples   []string
+}
+
+func (b *Builder) commandNotes(command *cli.Command) *commandNotes {
+	if b.notes == nil {
+		b.notes = make(map[*cli.Command]*commandNotes)
+	}
+	if b.notes[command] == nil {
+		b.notes[command] = &commandNotes{}
+	}
+	return b.notes[command]
+}
+
+// Sets how positional arguments of the most recently specified command are shown in usage, e.g. <src> <dst>
+func (b *Builder) ArgsUsage(argsUsage string) *Builder {
+	b.currentCommand("args usage").ArgsUsage = argsUsage
+	return b
+}
+
+// Adds deprecation, examples and constraints sections to the help of commands having any, groups
+// get them in their description since their help is not rendered from a custom template
+func (b *Builder) commandHelp() {
+	var walk func(commands []*cli.Command)
+	walk = func(commands []*cli.Command) {
+		for _, command := range commands {
+			walk(command.Subcommands)
+			var headings, bodies []string
+			if notes := b.notes[command]; notes != nil && len(notes.deprecated) > 0 {
+				headings, bodies = append(headings, "Deprecated"), append(bodies, notes.deprecated)
+			}
+			if notes := b.notes[command]; notes != nil && len(notes.examples) > 0 {
+				headings, bodies = append(headings, "Examples"), append(bodies, strings.Join(notes.examples, "\n"))
+			}
+			if constraints := b.constraints[command]; len(constraints) > 0 {
+				headings, bodies = append(headings, "Constraints"), append(bodies, constraintHelp(constraints))
+			}
+			if len(headings) == 0 {
+				continue
+			}
+			var sections []string
+			if len(command.Subcommands) > 0 {
+				for i, heading := range headings {
+					sections = append(sections, heading+":\n  "+strings.ReplaceAll(bodies[i], "\n", "\n  "))
+				}
+				command.Description = strings.TrimSpace(command.Description + "\n\n" + strings.Join(sections, "\n\n"))
+			} else if len(command.CustomHelpTemplate) == 0 {
+				for i, heading := range headings {
+					sections = append(sections, strings.ToUpper(heading)+":\n   "+strings.ReplaceAll(bodies[i], "\n", "\n   "))
+				}
+				extra := strings.Join(sections, "\n\n") + "\n"
+				command.CustomHelpTemplate = cli.CommandHelpTemplate + strings.ReplaceAll(extra, "{{", `{{"{{"}}`)
+			}
+		}
+	}
+	walk(b.app.Commands)
+}
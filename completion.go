@@ -0,0 +1,323 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"github.com/urfave/cli/v2/altsrc"
+)
+
+// Name of the hidden command invoked by completion scripts, prints the candidates completing the last word
+const completeCommandName = "__complete"
+
+// Completer returns the candidates completing prefix, the word being typed
+type Completer func(prefix string) []string
+
+// Flag or positional argument of a command, global flags are held by the nil command
+type completionTarget struct {
+	command  *cli.Command
+	flag     string
+	argument string
+}
+
+// Completes built in flags taking a value
+var builtinCompleters = map[string]Completer{
+	"config-file":     CompleteFiles,
+	"config-key-file": CompleteFiles,
+	"dump-target":     CompleteFiles,
+	"dump-format":     CompleteValues("json", "jsonc", "yaml", "toml"),
+}
+
+var completionScripts = map[string]string{
+	"bash": `# bash completion for {{name}}, e.g. source <({{name}} completion bash)
+_{{function}}_complete() {
+    local line="${COMP_LINE:0:COMP_POINT}" words cur
+    read -ra words <<< "$line"
+    if [[ "$line" == *" " ]]; then
+        words+=("")
+    fi
+    cur="${words[${#words[@]}-1]}"
+    local IFS=$'\n'
+    COMPREPLY=($({{name}} {{complete}} "${words[@]:1}" 2>/dev/null))
+    if [[ "$cur" == *=* && "$COMP_WORDBREAKS" == *=* ]]; then
+        COMPREPLY=("${COMPREPLY[@]#"${cur%=*}="}")
+    fi
+    if [[ ${#COMPREPLY[@]} -eq 1 && "${COMPREPLY[0]}" == */ ]]; then
+        compopt -o nospace
+    fi
+}
+complete -F _{{function}}_complete {{name}}
+`,
+	"zsh": `#compdef {{name}}
+# zsh completion for {{name}}, e.g. source <({{name}} completion zsh)
+_{{function}}_complete() {
+    local -a candidates dirs
+    candidates=(${(f)"$({{name}} {{complete}} "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    dirs=(${(M)candidates:#*/})
+    candidates=(${candidates:#*/})
+    (( ${#dirs} )) && compadd -S '' -- $dirs
+    (( ${#candidates} )) && compadd -- $candidates
+}
+compdef _{{function}}_complete {{name}}
+`,
+	"fish": `# fish completion for {{name}}, e.g. {{name}} completion fish | source
+function __{{function}}_complete
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l current (commandline -ct)
+    {{name}} {{complete}} $tokens "$current" 2>/dev/null
+end
+complete -c {{name}} -f -a '(__{{function}}_complete)'
+`,
+}
+
+// Adds a completion command printing a completion script for bash, zsh or fish, which completes commands,
+// flags including those generated from the config struct, enum values and file paths
+func (b *Builder) CompletionCommand() *Builder {
+	if b.completion {
+		return b
+	}
+	b.completion = true
+	b.Command("completion", "prints a shell completion script", func(app *Runner, args Args, flags Flags) error {
+		script, ok := completionScripts[args.String(0)]
+		if !ok || len(args) != 1 {
+			return fmt.Errorf("expected exactly one argument: bash, zsh or fish")
+		}
+		fmt.Print(strings.NewReplacer(
+			"{{name}}", b.app.Name,
+			"{{function}}", regexp.MustCompile(`\W`).ReplaceAllString(b.app.Name, "_"),
+			"{{complete}}", completeCommandName,
+		).Replace(script))
+		return nil
+	})
+	command := b.command
+	command.ArgsUsage = "<shell>"
+	b.addCompleter(completionTarget{command: command, argument: "shell"}, CompleteValues("bash", "zsh", "fish"))
+	b.command = nil
+	return b
+}
+
+// Sets completer of the values of a flag of the most recently specified command, or of a global flag when
+// called before any command or after Global()
+func (b *Builder) CompleteFlag(name string, completer Completer) *Builder {
+	b.addCompleter(completionTarget{command: b.command, flag: name}, completer)
+	return b
+}
+
+// Sets completer of a positional argument, by the name shown in usage, of the most recently specified command
+func (b *Builder) CompleteArgument(name string, completer Completer) *Builder {
+	b.addCompleter(completionTarget{command: b.currentCommand("complete argument"), argument: name}, completer)
+	return b
+}
+
+func (b *Builder) addCompleter(target completionTarget, completer Completer) {
+	if b.completers == nil {
+		b.completers = make(map[completionTarget]Completer)
+	}
+	b.completers[target] = completer
+}
+
+// Returns a completer of values starting with the prefix
+func CompleteValues(values ...string) Completer {
+	return func(prefix string) (candidates []string) {
+		for _, value := range values {
+			if strings.HasPrefix(value, prefix) {
+				candidates = append(candidates, value)
+			}
+		}
+		return candidates
+	}
+}
+
+// Completes file paths, directories end with a slash, hidden files are only listed if prefix names one
+func CompleteFiles(prefix string) (candidates []string) {
+	dir, base := filepath.Split(prefix)
+	read := dir
+	if len(read) == 0 {
+		read = "."
+	}
+	entries, err := os.ReadDir(read)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), base) || (strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		candidate := dir + entry.Name()
+		if entry.IsDir() {
+			candidate += "/"
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// Writes the candidates completing the last of words, the words following the program name on the command line
+func (b *Builder) complete(w io.Writer, words []string) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+	lineage := []*cli.Command{nil}
+	commands, flags := b.app.Commands, b.app.Flags
+	var positional []string
+	var pending cli.Flag // Flag whose value is the next word
+	terminated := false
+	for _, word := range words[:len(words)-1] {
+		if pending != nil {
+			pending = nil
+			continue
+		}
+		if word == "--" && !terminated {
+			terminated = true
+			continue
+		}
+		if strings.HasPrefix(word, "-") && len(word) > 1 && !terminated {
+			if flag := findFlag(flags, strings.TrimLeft(word, "-")); flag != nil && flagTakesValue(flag) && !strings.Contains(word, "=") {
+				pending = flag
+			}
+			continue
+		}
+		if command := findCommand(commands, word); command != nil && len(positional) == 0 {
+			lineage = append(lineage, command)
+			commands, flags = command.Subcommands, command.Flags
+			continue
+		}
+		positional = append(positional, word)
+	}
+
+	var candidates []string
+	command := lineage[len(lineage)-1]
+	switch {
+	case pending != nil:
+		candidates = b.flagCompleter(lineage, pending)(current)
+	case strings.HasPrefix(current, "-") && !terminated && strings.Contains(current, "="):
+		name := current[:strings.Index(current, "=")]
+		if flag := findFlag(flags, strings.TrimLeft(name, "-")); flag != nil {
+			for _, candidate := range b.flagCompleter(lineage, flag)(current[len(name)+1:]) {
+				candidates = append(candidates, name+"="+candidate)
+			}
+		}
+	case strings.HasPrefix(current, "-") && !terminated:
+		for _, flag := range append(append([]cli.Flag{}, flags...), cli.HelpFlag) {
+			if flagHidden(flag) {
+				continue
+			}
+			for _, name := range flag.Names() {
+				if name = prefixFor(name) + name; strings.HasPrefix(name, current) {
+					candidates = append(candidates, name)
+				}
+			}
+		}
+	case len(commands) > 0 && len(positional) == 0:
+		for _, command := range commands {
+			if command.Hidden {
+				continue
+			}
+			for _, name := range command.Names() {
+				if strings.HasPrefix(name, current) {
+					candidates = append(candidates, name)
+				}
+			}
+		}
+	case command != nil:
+		names := argumentNames(command.ArgsUsage)
+		if len(names) == 0 {
+			break
+		}
+		index := len(positional)
+		if index >= len(names) {
+			if !strings.HasSuffix(names[len(names)-1], "...") {
+				break
+			}
+			index = len(names) - 1
+		}
+		if completer, ok := b.completers[completionTarget{command: command, argument: strings.TrimSuffix(names[index], "...")}]; ok {
+			candidates = completer(current)
+		}
+	}
+	for _, candidate := range candidates {
+		fmt.Fprintln(w, candidate)
+	}
+}
+
+// Returns the completer of a flag set on the command closest to it, defaulting to enum values and file paths
+func (b *Builder) flagCompleter(lineage []*cli.Command, flag cli.Flag) Completer {
+	name := flag.Names()[0]
+	for i := len(lineage) - 1; i >= 0; i-- {
+		if completer, ok := b.completers[completionTarget{command: lineage[i], flag: name}]; ok {
+			return completer
+		}
+	}
+	if completer, ok := builtinCompleters[name]; ok && len(lineage) == 1 {
+		return completer
+	}
+	switch flag := flag.(type) {
+	case *altsrc.GenericFlag:
+		return genericCompleter(flag.GenericFlag)
+	case *cli.GenericFlag:
+		return genericCompleter(flag)
+	case *altsrc.PathFlag, *cli.PathFlag:
+		return CompleteFiles
+	}
+	return CompleteValues()
+}
+
+func genericCompleter(flag *cli.GenericFlag) Completer {
+	if enum, ok := flag.Value.(*Enum); ok {
+		return CompleteValues(enum.Allowed()...)
+	}
+	return CompleteValues()
+}
+
+// Returns the names of positional arguments shown in usage, e.g. "<src> [files...]" gives src and files...
+func argumentNames(argsUsage string) (names []string) {
+	for _, field := range strings.Fields(argsUsage) {
+		name := strings.Trim(field, "<>[]")
+		if i := strings.Index(name, ":"); i >= 0 {
+			name = name[:i]
+		}
+		if strings.HasSuffix(field, "...>") || strings.HasSuffix(field, "...]") {
+			name = strings.TrimSuffix(name, "...") + "..."
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func findCommand(commands []*cli.Command, name string) *cli.Command {
+	for _, command := range commands {
+		if command.HasName(name) {
+			return command
+		}
+	}
+	return nil
+}
+
+func findFlag(flags []cli.Flag, name string) cli.Flag {
+	for _, flag := range flags {
+		for _, flagName := range flag.Names() {
+			if flagName == name {
+				return flag
+			}
+		}
+	}
+	return nil
+}
+
+func flagTakesValue(flag cli.Flag) bool {
+	docFlag, ok := flag.(cli.DocGenerationFlag)
+	return ok && docFlag.TakesValue()
+}
+
+func flagHidden(flag cli.Flag) bool {
+	hidden := reflect.Indirect(reflect.ValueOf(flag)).FieldByName("Hidden")
+	return hidden.IsValid() && hidden.Bool()
+}
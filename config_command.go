@@ -0,0 +1,499 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/ake-persson/mapslice-json"
+	"github.com/urfave/cli/v2"
+)
+
+// ConfigCommand registers the config command which inspects and edits the configuration file
+func (b *Builder) ConfigCommand() *Builder {
+	if b.config == nil {
+		panic("config command requires .Config() to be called first")
+	}
+	if b.configCommand {
+		return b
+	}
+	b.configCommand = true
+	b.app.Commands = append(b.app.Commands, &cli.Command{
+		Name:  "config",
+		Usage: "inspects and edits the configuration file",
+	})
+	b.SubCommand("config", "get", "prints the effective value of a configuration key, secret and encrypted values are masked", func(runner *Runner, args Args, flags Flags) error {
+		if len(args) != 1 {
+			return fmt.Errorf("expected exactly one argument: <key>")
+		}
+		flagName, _, ok := b.configKey(args[0])
+		if !ok {
+			return fmt.Errorf("unknown config key %s", args[0])
+		}
+		for _, item := range runner.flatConfig {
+			if item.Key == flagName {
+				fmt.Println(b.maskConfigValue(flagName, fmt.Sprint(item.Value)))
+			}
+		}
+		return nil
+	})
+	b.SubCommand("config", "set", "sets a configuration key in the configuration file", func(runner *Runner, args Args, flags Flags) error {
+		if len(args) != 2 {
+			return fmt.Errorf("expected exactly two arguments: <key> <value>")
+		}
+		flagName, _, ok := b.configKey(args[0])
+		if !ok {
+			return fmt.Errorf("unknown config key %s", args[0])
+		}
+		value, err := b.configFieldByName(flagName).parse(args[1])
+		if err != nil {
+			return err
+		}
+		document, err := readConfigDocument(runner.configFile)
+		if err != nil {
+			return err
+		}
+		if err := b.writeConfigDocument(runner.configFile, setConfigDocumentKey(document, flagName, value)); err != nil {
+			return err
+		}
+		log.Printf("%s was successfully set in %s", flagName, runner.configFile)
+		return nil
+	})
+	b.SubCommand("config", "unset", "removes a configuration key from the configuration file, resetting it to its default", func(runner *Runner, args Args, flags Flags) error {
+		if len(args) != 1 {
+			return fmt.Errorf("expected exactly one argument: <key>")
+		}
+		flagName, _, ok := b.configKey(args[0])
+		if !ok {
+			return fmt.Errorf("unknown config key %s", args[0])
+		}
+		document, err := readConfigDocument(runner.configFile)
+		if err != nil {
+			return err
+		}
+		if err := b.writeConfigDocument(runner.configFile, unsetConfigDocumentKey(document, flagName)); err != nil {
+			return err
+		}
+		log.Printf("%s was successfully reset to its default in %s", flagName, runner.configFile)
+		return nil
+	})
+	b.SubCommand("config", "list", "lists the effective configuration, secret and encrypted values are masked", func(runner *Runner, args Args, flags Flags) error {
+		for _, item := range runner.flatConfig {
+			fmt.Printf("%s=%s\n", item.Key, b.maskConfigValue(fmt.Sprint(item.Key), fmt.Sprint(item.Value)))
+		}
+		return nil
+	})
+	b.SubCommand("config", "edit", "opens the configuration file in $EDITOR and validates it before saving", func(runner *Runner, args Args, flags Flags) error {
+		if runner.configFile == "-" {
+			return fmt.Errorf("cannot edit configuration read from stdin")
+		}
+		format := configFormatOf(runner.configFile)
+		original, err := ioutil.ReadFile(runner.configFile)
+		if os.IsNotExist(err) {
+			original, err = b.renderConfig(b.dumpDocument(), format)
+		}
+		if err != nil {
+			return err
+		}
+		tmp, err := ioutil.TempFile("", "config-*."+format)
+		if err != nil {
+			return err
+		}
+		_, err = tmp.Write(original)
+		tmp.Close()
+		if err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+		if err := runEditor(tmp.Name()); err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+		edited, err := ioutil.ReadFile(tmp.Name())
+		if err != nil {
+			return err
+		}
+		if err := b.validateConfig(edited, format); err != nil {
+			return fmt.Errorf("%s\nconfiguration was not saved, your changes are kept in %s", err, tmp.Name())
+		}
+		os.Remove(tmp.Name())
+		if string(edited) == string(original) {
+			log.Println("configuration was not changed")
+			return nil
+		}
+		if err := writeFileAtomic(runner.configFile, edited, true); err != nil {
+			return err
+		}
+		log.Printf("%s was successfully saved", runner.configFile)
+		return nil
+	})
+	b.SubCommand("config", "init", "walks through every configuration key and writes a new configuration file", func(runner *Runner, args Args, flags Flags) error {
+		if err := b.configInit(runner, flags); err != nil {
+			return err
+		}
+		log.Printf("%s was successfully written", runner.configFile)
+		return nil
+	},
+		BooleanFlag("non-interactive", "Takes answers from env vars or an answers file instead of prompting"),
+		StringFlag("answers", "Json file with answers, implies --non-interactive"),
+		BooleanFlag("force", "Overwrites an existing configuration file"),
+	)
+	b.SubCommand("config", "diff", "compares two of: defaults, effective or a configuration file, defaults to defaults and effective", func(runner *Runner, args Args, flags Flags) error {
+		if err := b.configDiff(runner, args, flags.String("output")); err != nil {
+			return err
+		}
+		return nil
+	}, StringFlag("output", "Output as unified or json"))
+	b.SubCommand("config", "encrypt", "encrypts a value and stores it for a configuration key, prompts for the value if omitted", func(runner *Runner, args Args, flags Flags) error {
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("expected one or two arguments: <key> [value]")
+		}
+		if len(runner.configKeyFile) == 0 {
+			return fmt.Errorf("specify a key file with --config-key-file, it is created if missing")
+		}
+		flagName, _, ok := b.configKey(args[0])
+		if !ok {
+			return fmt.Errorf("unknown config key %s", args[0])
+		}
+		var plaintext string
+		if len(args) == 2 {
+			plaintext = args[1]
+		} else {
+			var err error
+			if plaintext, err = readSecret(fmt.Sprintf("%s: ", flagName)); err != nil {
+				return err
+			}
+		}
+		value, err := b.configFieldByName(flagName).parse(plaintext)
+		if err != nil {
+			return err
+		}
+		key, created, err := loadOrCreateEncryptionKey(runner.configKeyFile)
+		if err != nil {
+			return err
+		}
+		if created {
+			log.Printf("new key was created in %s, keep it out of version control", runner.configKeyFile)
+		}
+		encrypted, err := encryptValue(key, flagName, fmt.Sprint(value))
+		if err != nil {
+			return err
+		}
+		if flags.Boolean("print") {
+			fmt.Println(encrypted)
+			return nil
+		}
+		document, err := readConfigDocument(runner.configFile)
+		if err != nil {
+			return err
+		}
+		if err := b.writeConfigDocument(runner.configFile, setConfigDocumentKey(document, flagName, encrypted)); err != nil {
+			return err
+		}
+		log.Printf("%s was successfully encrypted in %s", flagName, runner.configFile)
+		return nil
+	}, BooleanFlag("print", "Prints the encrypted value instead of storing it"))
+	b.SubCommand("config", "decrypt", "prints the decrypted value of an encrypted configuration key", func(runner *Runner, args Args, flags Flags) error {
+		if len(args) != 1 {
+			return fmt.Errorf("expected exactly one argument: <key>")
+		}
+		flagName, _, ok := b.configKey(args[0])
+		if !ok {
+			return fmt.Errorf("unknown config key %s", args[0])
+		}
+		document, err := readConfigDocument(runner.configFile)
+		if err != nil {
+			return err
+		}
+		value, ok := configDocumentValue(document, flagName)
+		if !ok {
+			return fmt.Errorf("%s is not set in %s", flagName, runner.configFile)
+		} else if !isEncryptedValue(value) {
+			return fmt.Errorf("%s is not encrypted in %s", flagName, runner.configFile)
+		}
+		key, err := loadEncryptionKey(runner.configKeyFile)
+		if err != nil {
+			return err
+		}
+		plaintext, err := decryptValue(key, flagName, value.(string))
+		if err != nil {
+			return err
+		}
+		fmt.Println(plaintext)
+		return nil
+	})
+	b.SubCommand("config", "validate", "validates a configuration file", func(runner *Runner, args Args, flags Flags) error {
+		if len(args) != 1 {
+			return fmt.Errorf("expected exactly one argument: <file>")
+		}
+		bts, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		if err := b.validateConfig(bts, configFormatOf(args[0])); err != nil {
+			return err
+		}
+		log.Printf("%s is valid", args[0])
+		return nil
+	})
+	b.command = nil // Modifiers following ConfigCommand() apply globally
+	return b
+}
+
+// Returns true if invoked command repairs the config file and must work even when loading it fails
+func (b *Builder) configRepairInvoked(c *cli.Context) bool {
+	if !b.configCommand || c.Args().First() != "config" {
+		return false
+	}
+	switch c.Args().Get(1) {
+	case "set", "unset", "edit", "init", "encrypt", "validate":
+		return true
+	}
+	return false
+}
+
+// Validates keys and values of a config document, including its profiles
+func (b *Builder) validateConfig(bts []byte, format string) error {
+	layer, err := parseConfigLayer(bts, format)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s", format, err)
+	}
+	problems := b.configLayerProblems(layer, "")
+	if profiles, ok := layer["profiles"]; ok {
+		profileMap, ok := profiles.(map[string]interface{})
+		if !ok {
+			problems = append(problems, "profiles must be an object")
+		}
+		for name, profile := range profileMap {
+			values, ok := profile.(map[string]interface{})
+			if !ok {
+				problems = append(problems, fmt.Sprintf("profile %s must be an object", name))
+				continue
+			}
+			profileLayer := make(configLayer)
+			profileLayer.merge(values, "")
+			problems = append(problems, b.configLayerProblems(profileLayer, fmt.Sprintf("profile %s: ", name))...)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Lists unknown keys and invalid values of a layer
+func (b *Builder) configLayerProblems(layer configLayer, prefix string) (problems []string) {
+	for key, value := range layer {
+		if key == "profiles" {
+			continue
+		}
+		field := b.configFieldByName(key)
+		if field == nil {
+			problems = append(problems, fmt.Sprintf("%sunknown key %s", prefix, key))
+		} else if isEncryptedValue(value) {
+			continue
+		} else if err := field.check(value); err != nil {
+			problems = append(problems, prefix+err.Error())
+		}
+	}
+	return problems
+}
+
+// Reads the top level keys of a config file in order, a missing file yields an empty document
+func readConfigDocument(path string) (mapslice.MapSlice, error) {
+	if path == "-" {
+		return nil, fmt.Errorf("cannot modify configuration read from stdin")
+	}
+	bts, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return mapslice.MapSlice{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	document, err := decodeConfigDocument(bts, configFormatOf(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %s", path, err)
+	}
+	return document, nil
+}
+
+// Atomically writes a config document in the format of its file extension, keeping a backup of the previous file
+func (b *Builder) writeConfigDocument(path string, document mapslice.MapSlice) error {
+	bts, err := b.renderConfig(document, configFormatOf(path))
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, bts, true)
+}
+
+// Sets a config key in a document where it is stored, flat or nested, dropping copies which would not take
+// effect. A new key is added to the nested object of its group if the document has one
+func setConfigDocumentKey(document mapslice.MapSlice, key string, value interface{}) mapslice.MapSlice {
+	paths := configDocumentPaths(document, key)
+	if len(paths) == 0 {
+		parent, rest := configDocumentParent(document, key)
+		if parent == nil {
+			return append(document, mapslice.MapItem{Key: key, Value: value})
+		}
+		configDocumentObject(document, parent)[rest] = value
+		return document
+	}
+	for _, path := range paths[:len(paths)-1] {
+		document = unsetConfigDocumentPath(document, path)
+	}
+	path := paths[len(paths)-1]
+	if len(path) > 1 {
+		configDocumentObject(document, path[:len(path)-1])[path[len(path)-1]] = value
+		return document
+	}
+	for i := range document {
+		if document[i].Key == path[0] {
+			document[i].Value = value
+		}
+	}
+	return document
+}
+
+// Removes every copy of a config key from a document, flat or nested
+func unsetConfigDocumentKey(document mapslice.MapSlice, key string) mapslice.MapSlice {
+	for _, path := range configDocumentPaths(document, key) {
+		document = unsetConfigDocumentPath(document, path)
+	}
+	return document
+}
+
+// Returns the value of a config key in a document, taken from the copy which takes effect
+func configDocumentValue(document mapslice.MapSlice, key string) (interface{}, bool) {
+	paths := configDocumentPaths(document, key)
+	if len(paths) == 0 {
+		return nil, false
+	}
+	path := paths[len(paths)-1]
+	if len(path) > 1 {
+		return configDocumentObject(document, path[:len(path)-1])[path[len(path)-1]], true
+	}
+	for _, item := range document {
+		if item.Key == path[0] {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Returns the paths at which a config key is stored in a document, flat or within nested objects whose keys
+// are joined by dashes, ordered by precedence so the last one takes effect
+func configDocumentPaths(document mapslice.MapSlice, key string) (paths [][]string) {
+	var find func(name string, value interface{}, key string, path []string)
+	find = func(name string, value interface{}, key string, path []string) {
+		path = append(append([]string{}, path...), name)
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			if name == key {
+				paths = append(paths, path)
+			}
+			return
+		}
+		if strings.HasPrefix(key, name+"-") {
+			for child, childValue := range nested {
+				find(child, childValue, key[len(name)+1:], path)
+			}
+		}
+	}
+	for _, item := range document {
+		if name := fmt.Sprint(item.Key); name != "profiles" {
+			find(name, item.Value, key, nil)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return configPathLess(paths[i], paths[j])
+	})
+	return paths
+}
+
+// Returns the path of the most deeply nested object of a document a new config key belongs in, together
+// with the remainder of the key, or a nil path if the key belongs at the top level
+func configDocumentParent(document mapslice.MapSlice, key string) (parent []string, rest string) {
+	objects := make(map[string]interface{})
+	for _, item := range document {
+		if name := fmt.Sprint(item.Key); name != "profiles" {
+			objects[name] = item.Value
+		}
+	}
+	rest = key
+	for {
+		var names []string
+		for name := range objects {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		found := false
+		for _, name := range names {
+			if nested, ok := objects[name].(map[string]interface{}); ok && strings.HasPrefix(rest, name+"-") {
+				parent, rest, objects, found = append(parent, name), rest[len(name)+1:], nested, true
+				break
+			}
+		}
+		if !found {
+			return parent, rest
+		}
+	}
+}
+
+// Returns the nested object of a document at path
+func configDocumentObject(document mapslice.MapSlice, path []string) (object map[string]interface{}) {
+	for _, item := range document {
+		if item.Key == path[0] {
+			object, _ = item.Value.(map[string]interface{})
+		}
+	}
+	for _, name := range path[1:] {
+		object, _ = object[name].(map[string]interface{})
+	}
+	return object
+}
+
+// Removes the value at path from a document, nested objects left empty are removed as well
+func unsetConfigDocumentPath(document mapslice.MapSlice, path []string) mapslice.MapSlice {
+	for ; len(path) > 1; path = path[:len(path)-1] {
+		object := configDocumentObject(document, path[:len(path)-1])
+		delete(object, path[len(path)-1])
+		if len(object) > 0 {
+			return document
+		}
+	}
+	for i, item := range document {
+		if item.Key == path[0] {
+			return append(document[:i], document[i+1:]...)
+		}
+	}
+	return document
+}
+
+// Opens file in the editor given by $VISUAL or $EDITOR and waits for it to exit
+func runEditor(path string) error {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if len(editor) == 0 {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if len(editor) == 0 {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %s", editor, err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ake-persson/mapslice-json"
+)
+
+func TestSetConfigDocumentKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		key      string
+		value    interface{}
+		expected string
+	}{
+		{"flat", `{"a":1,"db-host":"x"}`, "db-host", "y", `{"a":1,"db-host":"y"}`},
+		{"new flat", `{"a":1}`, "db-host", "y", `{"a":1,"db-host":"y"}`},
+		{"nested", `{"db":{"host":"x","port":1}}`, "db-host", "y", `{"db":{"host":"y","port":1}}`},
+		{"new in nested group", `{"db":{"port":1}}`, "db-host", "y", `{"db":{"host":"y","port":1}}`},
+		{"new in deepest group", `{"db":{"primary":{"port":1}}}`, "db-primary-host", "y", `{"db":{"primary":{"host":"y","port":1}}}`},
+		{"dashed group", `{"my-inner":{"my-int":1}}`, "my-inner-my-int", 2, `{"my-inner":{"my-int":2}}`},
+		{"both ways", `{"db-host":"x","db":{"host":"z"}}`, "db-host", "y", `{"db":{"host":"y"}}`},
+		{"both ways pruned", `{"db":{"host":"z"},"db-host":"x","a":1}`, "db-host", "y", `{"db":{"host":"y"},"a":1}`},
+		{"profiles untouched", `{"profiles":{"prod":{"db-host":"p"}}}`, "db-host", "y", `{"profiles":{"prod":{"db-host":"p"}},"db-host":"y"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := setConfigDocumentKey(decodeTestDocument(t, test.document), test.key, test.value)
+			expectTestDocument(t, document, test.expected)
+			if value, ok := configDocumentValue(document, test.key); !ok || value != test.value {
+				t.Fatalf("expected %v to take effect, got %v", test.value, value)
+			}
+		})
+	}
+}
+
+func TestUnsetConfigDocumentKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		key      string
+		expected string
+	}{
+		{"flat", `{"a":1,"db-host":"x"}`, "db-host", `{"a":1}`},
+		{"nested", `{"db":{"host":"x","port":1}}`, "db-host", `{"db":{"port":1}}`},
+		{"nested pruned", `{"a":1,"db":{"primary":{"host":"x"}}}`, "db-primary-host", `{"a":1}`},
+		{"both ways", `{"db-host":"x","a":1,"db":{"host":"z"}}`, "db-host", `{"a":1}`},
+		{"missing", `{"a":1,"db":{"port":1}}`, "db-host", `{"a":1,"db":{"port":1}}`},
+		{"profiles untouched", `{"db-host":"x","profiles":{"prod":{"db-host":"p"}}}`, "db-host", `{"profiles":{"prod":{"db-host":"p"}}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := unsetConfigDocumentKey(decodeTestDocument(t, test.document), test.key)
+			expectTestDocument(t, document, test.expected)
+			if value, ok := configDocumentValue(document, test.key); ok {
+				t.Fatalf("expected %s to be removed, got %v", test.key, value)
+			}
+		})
+	}
+}
+
+func TestConfigDocumentValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		key      string
+		expected interface{}
+	}{
+		{"flat", `{"db-host":"x"}`, "db-host", "x"},
+		{"nested", `{"db":{"host":"z"}}`, "db-host", "z"},
+		{"nested wins", `{"db":{"host":"z"},"db-host":"x"}`, "db-host", "z"},
+		{"deepest wins", `{"db-primary-host":"x","db":{"primary-host":"y","primary":{"host":"z"}}}`, "db-primary-host", "z"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value, ok := configDocumentValue(decodeTestDocument(t, test.document), test.key); !ok || value != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, value)
+			}
+		})
+	}
+}
+
+func decodeTestDocument(t *testing.T, document string) mapslice.MapSlice {
+	t.Helper()
+	decoded, err := decodeConfigDocument([]byte(document), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func expectTestDocument(t *testing.T, document mapslice.MapSlice, expected string) {
+	t.Helper()
+	bts, err := json.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bts, []byte(expected)) {
+		t.Fatalf("expected %s, got %s", expected, bts)
+	}
+}
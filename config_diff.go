@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ake-persson/mapslice-json"
+)
+
+// Shown instead of values of fields tagged with secret:"true" and of encrypted values
+const maskedValue = "********"
+
+type configChange struct {
+	Key  string `json:"key"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+type configComparison struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Changed []configChange `json:"changed"`
+	Added   []configChange `json:"added"`
+	Removed []configChange `json:"removed"`
+}
+
+// Returns flat config values of a diff source: defaults, effective or a config file path, keys which
+// a file does not set take their default
+func (b *Builder) configSnapshot(runner *Runner, source string) (mapslice.MapSlice, error) {
+	var snapshot mapslice.MapSlice
+	switch source {
+	case "defaults":
+		for _, field := range b.configFields {
+			snapshot = append(snapshot, mapslice.MapItem{Key: field.name, Value: fmt.Sprint(b.configDefault(field.name))})
+		}
+	case "effective":
+		for _, item := range runner.flatConfig {
+			snapshot = append(snapshot, mapslice.MapItem{Key: item.Key, Value: fmt.Sprint(item.Value)})
+		}
+	default:
+		layer, err := loadConfigFile(source, true)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range b.configFields {
+			if value, ok := layer[field.name]; ok {
+				snapshot = append(snapshot, mapslice.MapItem{Key: field.name, Value: strings.Join(layerValues(value), ",")})
+			} else {
+				snapshot = append(snapshot, mapslice.MapItem{Key: field.name, Value: fmt.Sprint(b.configDefault(field.name))})
+			}
+		}
+		var unknown []string
+		for key := range layer {
+			if key != "profiles" && b.configFieldByName(key) == nil {
+				unknown = append(unknown, key)
+			}
+		}
+		sort.Strings(unknown)
+		for _, key := range unknown {
+			snapshot = append(snapshot, mapslice.MapItem{Key: key, Value: strings.Join(layerValues(layer[key]), ",")})
+		}
+	}
+	return snapshot, nil
+}
+
+// Compares two snapshots, values of secret fields and encrypted values are masked
+func (b *Builder) compareConfig(fromName string, from mapslice.MapSlice, toName string, to mapslice.MapSlice) configComparison {
+	comparison := configComparison{From: fromName, To: toName, Changed: []configChange{}, Added: []configChange{}, Removed: []configChange{}}
+	toValues := make(map[string]string)
+	for _, item := range to {
+		toValues[fmt.Sprint(item.Key)] = fmt.Sprint(item.Value)
+	}
+	fromValues := make(map[string]string)
+	for _, item := range from {
+		key, value := fmt.Sprint(item.Key), fmt.Sprint(item.Value)
+		fromValues[key] = value
+		toValue, exists := toValues[key]
+		if !exists {
+			comparison.Removed = append(comparison.Removed, configChange{Key: key, From: b.maskConfigValue(key, value)})
+		} else if toValue != value {
+			change := configChange{Key: key, From: b.maskConfigValue(key, value), To: b.maskConfigValue(key, toValue)}
+			if change.From == change.To {
+				change.To += " (differs)"
+			}
+			comparison.Changed = append(comparison.Changed, change)
+		}
+	}
+	for _, item := range to {
+		key := fmt.Sprint(item.Key)
+		if _, exists := fromValues[key]; !exists {
+			comparison.Added = append(comparison.Added, configChange{Key: key, To: b.maskConfigValue(key, fmt.Sprint(item.Value))})
+		}
+	}
+	return comparison
+}
+
+// Renders the comparison as a unified diff of key = value lines
+func (b *Builder) unifiedConfigDiff(from mapslice.MapSlice, comparison configComparison) string {
+	changed := make(map[string]configChange)
+	for _, change := range comparison.Changed {
+		changed[change.Key] = change
+	}
+	var fromLines, toLines strings.Builder
+	for _, item := range from {
+		key := fmt.Sprint(item.Key)
+		value := b.maskConfigValue(key, fmt.Sprint(item.Value))
+		fmt.Fprintf(&fromLines, "%s = %s\n", key, value)
+		if change, ok := changed[key]; ok {
+			fmt.Fprintf(&toLines, "%s = %s\n", key, change.To)
+			continue
+		}
+		removed := false
+		for _, change := range comparison.Removed {
+			removed = removed || change.Key == key
+		}
+		if !removed {
+			fmt.Fprintf(&toLines, "%s = %s\n", key, value)
+		}
+	}
+	for _, change := range comparison.Added {
+		fmt.Fprintf(&toLines, "%s = %s\n", change.Key, change.To)
+	}
+	return unifiedDiff(comparison.From, comparison.To, fromLines.String(), toLines.String())
+}
+
+// Masks values of fields tagged with secret:"true" and values decrypted from enc:v1: values of the config
+func (b *Builder) maskConfigValue(key string, value string) string {
+	if field := b.configFieldByName(key); field != nil && field.secret() && len(value) > 0 {
+		return maskedValue
+	}
+	return fmt.Sprint(b.runner.shownConfigValue(key, value))
+}
+
+// Compares two config sources and prints the result as unified diff or json
+func (b *Builder) configDiff(runner *Runner, args Args, output string) error {
+	if len(args) > 2 {
+		return fmt.Errorf("expected at most two arguments: [from] [to]")
+	}
+	fromName, toName := "defaults", "effective"
+	if len(args) > 0 {
+		fromName = args[0]
+	}
+	if len(args) > 1 {
+		toName = args[1]
+	}
+	from, err := b.configSnapshot(runner, fromName)
+	if err != nil {
+		return err
+	}
+	to, err := b.configSnapshot(runner, toName)
+	if err != nil {
+		return err
+	}
+	comparison := b.compareConfig(fromName, from, toName, to)
+	switch output {
+	case "", "unified":
+		fmt.Print(b.unifiedConfigDiff(from, comparison))
+	case "json":
+		bts, err := json.MarshalIndent(comparison, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(bts))
+	default:
+		return fmt.Errorf("unsupported output %s, expected unified or json", output)
+	}
+	return nil
+}
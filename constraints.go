@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Kinds of flag constraints
+const (
+	constraintRequired   = "required"
+	constraintExclusive  = "exclusive"
+	constraintRequires   = "requires"
+	constraintAtLeastOne = "at-least-one"
+)
+
+// Describes a rule on which flags must, or must not, be set together
+type flagConstraint struct {
+	kind     string
+	flags    []string
+	required []string // Flags required by flags[0] of a requires constraint
+}
+
+// Makes flags required for the most recently specified command, or globally before any command or after Global()
+func (b *Builder) Required(flags ...string) *Builder {
+	return b.addConstraint(flagConstraint{kind: constraintRequired, flags: flags})
+}
+
+// Makes flags mutually exclusive for the most recently specified command, or globally before any command or after Global()
+func (b *Builder) Exclusive(flags ...string) *Builder {
+	return b.addConstraint(flagConstraint{kind: constraintExclusive, flags: flags})
+}
+
+// Makes flag depend on the required flags for the most recently specified command, or globally before any command or after Global()
+func (b *Builder) Requires(flag string, required ...string) *Builder {
+	return b.addConstraint(flagConstraint{kind: constraintRequires, flags: []string{flag}, required: required})
+}
+
+// Requires at least one of flags for the most recently specified command, or globally before any command or after Global()
+func (b *Builder) AtLeastOneOf(flags ...string) *Builder {
+	return b.addConstraint(flagConstraint{kind: constraintAtLeastOne, flags: flags})
+}
+
+func (b *Builder) addConstraint(constraint flagConstraint) *Builder {
+	if b.constraints == nil {
+		b.constraints = make(map[*cli.Command][]flagConstraint)
+	}
+	command := b.command
+	if command != nil && len(b.constraints[command]) == 0 && command.Action != nil {
+		b.addCheck(command, func(cc *cli.Context) error {
+			return checkConstraints(cc, b.constraints[command])
+		})
+	} else if command != nil && len(b.constraints[command]) == 0 {
+		before := command.Before // Groups are checked before any of their sub commands
+		command.Before = func(cc *cli.Context) error {
+			if err := checkConstraints(cc, b.constraints[command]); err != nil {
+				return err
+			}
+			if before != nil {
+				return before(cc)
+			}
+			return nil
+		}
+	}
+	b.constraints[command] = append(b.constraints[command], constraint)
+	return b
+}
+
+// Returns the first violated constraint
+func checkConstraints(c *cli.Context, constraints []flagConstraint) error {
+	for _, constraint := range constraints {
+		var set []string
+		for _, flag := range constraint.flags {
+			if isFlagSet(c, flag) {
+				set = append(set, flag)
+			}
+		}
+		switch constraint.kind {
+		case constraintRequired:
+			for _, flag := range constraint.flags {
+				if !isFlagSet(c, flag) {
+					return fmt.Errorf("%s%s is required", prefixFor(flag), flag)
+				}
+			}
+		case constraintExclusive:
+			if len(set) > 1 {
+				return fmt.Errorf("%s cannot be used together", joinFlags(set, " and "))
+			}
+		case constraintRequires:
+			if len(set) == 0 {
+				continue
+			}
+			for _, required := range constraint.required {
+				if !isFlagSet(c, required) {
+					return fmt.Errorf("%s%s requires %s%s", prefixFor(set[0]), set[0], prefixFor(required), required)
+				}
+			}
+		case constraintAtLeastOne:
+			if len(set) == 0 {
+				return fmt.Errorf("at least one of %s is required", joinFlags(constraint.flags, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func (c flagConstraint) String() string {
+	switch c.kind {
+	case constraintRequired:
+		return joinFlags(c.flags, ", ") + " required"
+	case constraintExclusive:
+		return joinFlags(c.flags, ", ") + " mutually exclusive"
+	case constraintRequires:
+		return joinFlags(c.flags, ", ") + " requires " + joinFlags(c.required, ", ")
+	case constraintAtLeastOne:
+		return "at least one of " + joinFlags(c.flags, ", ")
+	}
+	return ""
+}
+
+// Renders constraints as lines of a help section
+func constraintHelp(constraints []flagConstraint) string {
+	var lines []string
+	for _, constraint := range constraints {
+		lines = append(lines, constraint.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Returns true if the help or a built in command is invoked, these run without global constraints being met
+func (b *Builder) utilityCommandInvoked(c *cli.Context) bool {
+	switch c.Args().First() {
+	case "help", "h":
+		return true
+	}
+	return b.builtinCommandInvoked(c)
+}
+
+// Returns true if the config or completion command is invoked
+func (b *Builder) builtinCommandInvoked(c *cli.Context) bool {
+	switch c.Args().First() {
+	case "config":
+		return b.configCommand
+	case "completion":
+		return b.completion
+	}
+	return false
+}
+
+// Detects constraints naming flags which are not defined
+func (b *Builder) checkConstraintFlags() error {
+	var problems []string
+	for command, constraints := range b.constraints {
+		flags := b.app.Flags
+		prefix := ""
+		if command != nil {
+			flags = append(append([]cli.Flag{}, b.app.Flags...), command.Flags...)
+			prefix = fmt.Sprintf("command %s: ", command.Name)
+		}
+		defined := make(map[string]bool)
+		for _, flag := range flags {
+			for _, name := range flag.Names() {
+				defined[name] = true
+			}
+		}
+		for _, constraint := range constraints {
+			for _, flag := range append(append([]string{}, constraint.flags...), constraint.required...) {
+				if !defined[flag] {
+					problems = append(problems, fmt.Sprintf("%sconstraint %q names undefined flag %s%s", prefix, constraint, prefixFor(flag), flag))
+				}
+			}
+		}
+	}
+	sort.Strings(problems)
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid flag constraints:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func joinFlags(flags []string, separator string) string {
+	prefixed := make([]string, len(flags))
+	for i, flag := range flags {
+		prefixed[i] = prefixFor(flag) + flag
+	}
+	return strings.Join(prefixed, separator)
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Prefix of encrypted config values, followed by base64 of nonce and AES-256-GCM sealed value, the
+// dashed name of the config key is authenticated as additional data
+const encryptedValuePrefix = "enc:v1:"
+
+// Size in bytes of the symmetric key held base64 encoded in the key file
+const encryptionKeySize = 32
+
+func isEncryptedValue(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, encryptedValuePrefix)
+}
+
+// Reads a base64 encoded key from file
+func loadEncryptionKey(path string) ([]byte, error) {
+	bts, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config key file: %s", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(bts)))
+	if err != nil || len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("config key file %s must contain a base64 encoded %d byte key", path, encryptionKeySize)
+	}
+	return key, nil
+}
+
+// Reads the key file, creating it with a new random key if it does not exist
+func loadOrCreateEncryptionKey(path string) (key []byte, created bool, err error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		key = make([]byte, encryptionKeySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, false, err
+		}
+		if err := ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+			return nil, false, err
+		}
+		return key, true, nil
+	}
+	key, err = loadEncryptionKey(path)
+	return key, false, err
+}
+
+// Encrypts plaintext of the config key by dashed name, the value only decrypts for the same key
+func encryptValue(key []byte, name string, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(name))
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypts value of the config key by dashed name, fails if it was encrypted for another key
+func decryptValue(key []byte, name string, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedValuePrefix))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value, wrong key, tampered value or value of another config key")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Returns a copy of the layer with encrypted values decrypted, the key file is only read when needed
+func (l configLayer) decrypt(keyFile string) (configLayer, error) {
+	var key []byte
+	decrypted := make(configLayer, len(l))
+	for name, value := range l {
+		decrypted[name] = value
+		if !isEncryptedValue(value) {
+			continue
+		}
+		if key == nil {
+			if len(keyFile) == 0 {
+				return nil, fmt.Errorf("%s is encrypted, specify a key file with --config-key-file", name)
+			}
+			var err error
+			if key, err = loadEncryptionKey(keyFile); err != nil {
+				return nil, err
+			}
+		}
+		plaintext, err := decryptValue(key, name, value.(string))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		decrypted[name] = plaintext
+	}
+	return decrypted, nil
+}
+
+// Returns the encrypted values of the layer by key
+func (l configLayer) encryptedValues() map[string]string {
+	values := make(map[string]string)
+	for name, value := range l {
+		if isEncryptedValue(value) {
+			values[name] = value.(string)
+		}
+	}
+	return values
+}
@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptedValueIsBoundToKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, encryptionKeySize)
+	encrypted, err := encryptValue(key, "db-password", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := decryptValue(key, "db-password", encrypted); err != nil || plaintext != "secret" {
+		t.Fatalf("expected secret, got %q, %v", plaintext, err)
+	}
+	if _, err := decryptValue(key, "db-url", encrypted); err == nil {
+		t.Fatalf("expected value moved to another key to fail decryption")
+	}
+}
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Enum is a flag value restricted to a list of allowed values
+type Enum struct {
+	allowed []string
+	value   string
+}
+
+// Returns an enum holding value, which is not checked against allowed
+func NewEnum(allowed []string, value string) *Enum {
+	return &Enum{allowed: allowed, value: value}
+}
+
+// Returns an enum holding the default value of a field, panics if it is neither empty nor allowed
+func defaultEnum(name string, allowed []string, value string) *Enum {
+	enum := NewEnum(allowed, "")
+	if err := enum.Set(value); err != nil {
+		panic(fmt.Sprintf("default of %s is invalid: %s", name, err))
+	}
+	return enum
+}
+
+// Returns the allowed values
+func (e *Enum) Allowed() []string {
+	return e.allowed
+}
+
+// Implements cli.Generic, fails with a did you mean hint if value is not allowed, an empty value leaves the
+// enum unset
+func (e *Enum) Set(value string) error {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		e.value = ""
+		return nil
+	}
+	for _, allowed := range e.allowed {
+		if value == allowed {
+			e.value = value
+			return nil
+		}
+	}
+	hint := ""
+	if suggestion := didYouMean(value, e.allowed); len(suggestion) > 0 {
+		hint = fmt.Sprintf(", did you mean %s?", suggestion)
+	}
+	return fmt.Errorf("%q is not one of %s%s", value, strings.Join(e.allowed, ", "), hint)
+}
+
+func (e *Enum) String() string {
+	if e == nil {
+		return ""
+	}
+	return e.value
+}
+
+// Implements flag.Getter
+func (e *Enum) Get() interface{} {
+	return e.value
+}
+
+// Describes the allowed values for help, e.g. {json|table}
+func (e *Enum) usage() string {
+	return "{" + strings.Join(e.allowed, "|") + "}"
+}
+
+// EnumFlag specifices a string flag variable restricted to allowed values input by provided name, usage and aliases
+func EnumFlag(name string, usage string, allowed []string, aliases ...string) cli.Flag {
+	value := NewEnum(allowed, "")
+	return &cli.GenericFlag{
+		Name:    name,
+		Usage:   strings.TrimSpace(usage + " " + value.usage()),
+		Value:   value,
+		Aliases: aliases,
+	}
+}
+
+// EnumFlag specifices a string flag variable restricted to allowed values input by provided name, usage and aliases
+func (b *Builder) EnumFlag(name string, usage string, allowed []string, aliases ...string) *Builder {
+	b.app.Flags = append(b.app.Flags, EnumFlag(name, usage, allowed, aliases...))
+	return b
+}
+
+// Returns the values of an enum:"a,b,c" tag, nil if the tag is missing
+func enumValues(tag string) (values []string) {
+	for _, value := range strings.Split(tag, ",") {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Returns the candidate closest to value, empty if none is close enough to be a likely typo
+func didYouMean(value string, candidates []string) (suggestion string) {
+	best := len(value)/3 + 2
+	for _, candidate := range candidates {
+		if distance := levenshtein(strings.ToLower(value), strings.ToLower(candidate)); distance < best {
+			best, suggestion = distance, candidate
+		}
+	}
+	return suggestion
+}
+
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ake-persson/mapslice-json"
+	"github.com/urfave/cli/v2"
+)
+
+// Describes a config struct field which is exposed as a flag
+type configField struct {
+	name    string // Dashed flag name, also the key used in config files
+	path    string // Struct path, e.g. MyInnerStruct.MyInnerInt
+	envVars []string
+	field   reflect.StructField
+	flag    cli.Flag
+}
+
+// Registers a config struct field together with its default value, must be called
+// right after the flag of the field has been appended to the app flags
+func (b *Builder) addConfigItem(flagName string, defaultValue interface{}, field reflect.StructField, path string) {
+	b.configStructure = append(b.configStructure, mapslice.MapItem{
+		Key:   flagName,
+		Value: defaultValue,
+	})
+	b.configFields = append(b.configFields, &configField{
+		name:    flagName,
+		path:    path,
+		envVars: envVars(env(flagName), field.Tag.Get("env")),
+		field:   field,
+		flag:    b.app.Flags[len(b.app.Flags)-1],
+	})
+}
+
+// Returns the registered config field by flag name
+func (b *Builder) configFieldByName(flagName string) *configField {
+	for _, field := range b.configFields {
+		if field.name == flagName {
+			return field
+		}
+	}
+	return nil
+}
+
+// Returns true if field is tagged with secret:"true" and its value should not be shown
+func (f *configField) secret() bool {
+	secret, _ := strconv.ParseBool(f.field.Tag.Get("secret"))
+	return secret
+}
+
+// Returns true if field is tagged with hidden:"true", its flag is then left out of the help
+func hiddenConfigField(field reflect.StructField) bool {
+	hidden, _ := strconv.ParseBool(field.Tag.Get("hidden"))
+	return hidden
+}
+
+// Returns true if field is not part of the config, either tagged with flag:"-" or unexported
+func skipConfigField(field reflect.StructField) bool {
+	if field.Tag.Get("flag") == "-" {
+		return true
+	}
+	return len(field.PkgPath) > 0 && !inlineConfigField(field)
+}
+
+// Returns true if field is an embedded struct, its fields are flattened into the parent without a prefix
+func inlineConfigField(field reflect.StructField) bool {
+	return field.Anonymous && field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{})
+}
+
+// Returns the struct path of the nested struct holding the field, empty for top level fields
+func (f *configField) group() string {
+	if i := strings.LastIndex(f.path, "."); i >= 0 {
+		return f.path[:i]
+	}
+	return ""
+}
+
+// Describes the values accepted by the field
+func (f *configField) constraints() (constraints []string) {
+	switch f.field.Type {
+	case reflect.TypeOf(time.Time{}):
+		return append(constraints, fmt.Sprintf("type: time as %s, %s or %s", dateTimeFormat, dateFormat, "15:04:05"))
+	case reflect.TypeOf(time.Duration(0)):
+		return append(constraints, "type: duration, e.g. 1m30s")
+	case reflect.TypeOf(ByteSize(0)):
+		return append(constraints, "type: byte size, e.g. 512MB or 1GiB")
+	case reflect.TypeOf(Percent(0)):
+		return append(constraints, "type: percentage, e.g. 75%")
+	}
+	switch f.field.Type.Kind() {
+	case reflect.Int:
+		constraints = append(constraints, "type: integer")
+	case reflect.Bool:
+		constraints = append(constraints, "type: true or false")
+	case reflect.Slice:
+		constraints = append(constraints, "type: comma separated list")
+	case reflect.String:
+		if allowed := enumValues(f.field.Tag.Get("enum")); len(allowed) > 0 {
+			constraints = append(constraints, "one of: "+strings.Join(allowed, ", "))
+		} else {
+			constraints = append(constraints, "type: string")
+		}
+	}
+	return constraints
+}
+
+// Converts a raw string into the value stored in config files, fails if it does not match the field type
+func (f *configField) parse(raw string) (interface{}, error) {
+	raw = strings.TrimSpace(raw)
+	switch f.field.Type {
+	case reflect.TypeOf(time.Time{}):
+		if _, err := parseTimeValue(raw); err != nil {
+			return nil, fmt.Errorf("%s expects a time as %s, %s or %s", f.name, dateTimeFormat, dateFormat, "15:04:05")
+		}
+		return raw, nil
+	case reflect.TypeOf(time.Duration(0)):
+		v, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects a duration, e.g. 1m30s", f.name)
+		}
+		return v.String(), nil
+	case reflect.TypeOf(ByteSize(0)):
+		v, err := ParseByteSize(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects a byte size, e.g. 512MB or 1GiB", f.name)
+		}
+		return v.String(), nil
+	case reflect.TypeOf(Percent(0)):
+		v, err := ParsePercent(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects a percentage, e.g. 75%%", f.name)
+		}
+		return v.String(), nil
+	}
+	switch f.field.Type.Kind() {
+	case reflect.Int:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects an integer", f.name)
+		}
+		return v, nil
+	case reflect.Bool:
+		v, err := parseConfigBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects true or false", f.name)
+		}
+		return v, nil
+	case reflect.Slice:
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); len(value) > 0 {
+				values = append(values, value)
+			}
+		}
+		return strings.Join(values, ","), nil
+	case reflect.String:
+		if allowed := enumValues(f.field.Tag.Get("enum")); len(allowed) > 0 {
+			if err := NewEnum(allowed, "").Set(raw); err != nil {
+				return nil, fmt.Errorf("%s: %s", f.name, err)
+			}
+		}
+	}
+	return raw, nil
+}
+
+// Validates a value decoded from a config file against the field type
+func (f *configField) check(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	switch f.field.Type.Kind() {
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s expects a string", f.name)
+		}
+	case reflect.Bool:
+		switch value.(type) {
+		case bool, string: // Strings such as yes are kept from yaml documents
+		default:
+			return fmt.Errorf("%s expects true or false", f.name)
+		}
+	}
+	_, err := f.parse(strings.Join(layerValues(value), ","))
+	return err
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Writes data to a temporary file next to path and renames it into place, an
+// existing file keeps its mode and is optionally copied to path.bak first
+func writeFileAtomic(path string, data []byte, backup bool) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+		if backup {
+			original, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(path+".bak", original, mode); err != nil {
+				return err
+			}
+		}
+	}
+	return writeFileAtomicMode(path, data, mode)
+}
+
+// Writes data to a temporary file next to path and renames it into place with mode, regardless of the
+// mode of an existing file
+func writeFileAtomicMode(path string, data []byte, mode os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -1,39 +1,280 @@
 package cli
 
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"github.com/urfave/cli/v2/altsrc"
+)
+
 type Flags map[string]interface{}
 
-// Returns boolean flag, also returns false if flag is missing
+// Returns boolean flag, also returns false if flag is missing or of another type
 func (f Flags) Boolean(name string) bool {
-	if f == nil {
-		return false
+	val, _ := f.LookupBoolean(name)
+	return val
+}
+
+// Returns integer flag, also returns 0 if flag is missing or of another type
+func (f Flags) Integer(name string) int {
+	val, _ := f.LookupInteger(name)
+	return val
+}
+
+// Returns string flag, also returns "" if flag is missing or of another type
+func (f Flags) String(name string) string {
+	val, _ := f.LookupString(name)
+	return val
+}
+
+// Returns float flag, also returns 0 if flag is missing or of another type
+func (f Flags) Float64(name string) float64 {
+	val, _ := f.LookupFloat64(name)
+	return val
+}
+
+// Returns 64 bit integer flag, also returns 0 if flag is missing or of another type
+func (f Flags) Int64(name string) int64 {
+	val, _ := f.LookupInt64(name)
+	return val
+}
+
+// Returns unsigned integer flag, also returns 0 if flag is missing or of another type
+func (f Flags) Uint(name string) uint {
+	val, _ := f.LookupUint(name)
+	return val
+}
+
+// Returns duration flag, also returns 0 if flag is missing or of another type
+func (f Flags) Duration(name string) time.Duration {
+	val, _ := f.LookupDuration(name)
+	return val
+}
+
+// Returns string slice flag, also returns nil if flag is missing or of another type
+func (f Flags) StringSlice(name string) []string {
+	val, _ := f.LookupStringSlice(name)
+	return val
+}
+
+// Returns integer slice flag, also returns nil if flag is missing or of another type
+func (f Flags) IntSlice(name string) []int {
+	val, _ := f.LookupIntSlice(name)
+	return val
+}
+
+// Returns timestamp flag, also returns the zero time if flag is missing or of another type
+func (f Flags) Timestamp(name string) time.Time {
+	val, _ := f.LookupTimestamp(name)
+	return val
+}
+
+// Returns path flag, also returns "" if flag is missing or of another type
+func (f Flags) Path(name string) string {
+	val, _ := f.LookupPath(name)
+	return val
+}
+
+// Returns boolean flag, fails if flag is missing or of another type
+func (f Flags) LookupBoolean(name string) (bool, error) {
+	val, err := f.lookup(name)
+	if err != nil {
+		return false, err
 	}
-	val, exists := f[name]
-	if !exists {
-		return false
+	if v, ok := val.(bool); ok {
+		return v, nil
 	}
-	return val.(bool)
+	return false, flagTypeError(name, val, "boolean")
 }
 
-// Returns integer flag, also returns 0 if flag is missing
-func (f Flags) Integer(name string) int {
-	if f == nil {
-		return 0
+// Returns integer flag, fails if flag is missing or of another type
+func (f Flags) LookupInteger(name string) (int, error) {
+	val, err := f.lookup(name)
+	if err != nil {
+		return 0, err
 	}
-	val, exists := f[name]
-	if !exists {
-		return 0
+	if v, ok := val.(int); ok {
+		return v, nil
 	}
-	return val.(int)
+	return 0, flagTypeError(name, val, "integer")
 }
 
-// Returns string flag, also returns 0 if flag is missing
-func (f Flags) String(name string) string {
-	if f == nil {
-		return ""
+// Returns string flag, fails if flag is missing or of another type
+func (f Flags) LookupString(name string) (string, error) {
+	val, err := f.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	if v, ok := val.(string); ok {
+		return v, nil
+	}
+	return "", flagTypeError(name, val, "string")
+}
+
+// Returns float flag, integer flags are converted, fails if flag is missing or of another type
+func (f Flags) LookupFloat64(name string) (float64, error) {
+	val, err := f.lookup(name)
+	if err != nil {
+		return 0, err
 	}
+	switch v := val.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	}
+	return 0, flagTypeError(name, val, "float")
+}
+
+// Returns 64 bit integer flag, integer flags are converted, fails if flag is missing or of another type
+func (f Flags) LookupInt64(name string) (int64, error) {
+	val, err := f.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	switch v := val.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	}
+	return 0, flagTypeError(name, val, "64 bit integer")
+}
+
+// Returns unsigned integer flag, fails if flag is missing or of another type
+func (f Flags) LookupUint(name string) (uint, error) {
+	val, err := f.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	if v, ok := val.(uint); ok {
+		return v, nil
+	}
+	return 0, flagTypeError(name, val, "unsigned integer")
+}
+
+// Returns duration flag, fails if flag is missing or of another type
+func (f Flags) LookupDuration(name string) (time.Duration, error) {
+	val, err := f.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	if v, ok := val.(time.Duration); ok {
+		return v, nil
+	}
+	return 0, flagTypeError(name, val, "duration")
+}
+
+// Returns string slice flag, fails if flag is missing or of another type
+func (f Flags) LookupStringSlice(name string) ([]string, error) {
+	val, err := f.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	switch v := val.(type) {
+	case cli.StringSlice:
+		return v.Value(), nil
+	case []string:
+		return v, nil
+	}
+	return nil, flagTypeError(name, val, "string slice")
+}
+
+// Returns integer slice flag, fails if flag is missing or of another type
+func (f Flags) LookupIntSlice(name string) ([]int, error) {
+	val, err := f.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	switch v := val.(type) {
+	case cli.IntSlice:
+		return v.Value(), nil
+	case []int:
+		return v, nil
+	}
+	return nil, flagTypeError(name, val, "integer slice")
+}
+
+// Returns timestamp flag, fails if flag is missing or of another type
+func (f Flags) LookupTimestamp(name string) (time.Time, error) {
+	val, err := f.lookup(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch v := val.(type) {
+	case cli.Timestamp:
+		if t := v.Value(); t != nil {
+			return *t, nil
+		}
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	}
+	return time.Time{}, flagTypeError(name, val, "timestamp")
+}
+
+// Returns path flag, fails if flag is missing or of another type
+func (f Flags) LookupPath(name string) (string, error) {
+	val, err := f.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	if v, ok := val.(string); ok {
+		return v, nil
+	}
+	return "", flagTypeError(name, val, "path")
+}
+
+func (f Flags) lookup(name string) (interface{}, error) {
 	val, exists := f[name]
 	if !exists {
-		return ""
+		return nil, fmt.Errorf("flag %s is not set", name)
+	}
+	return val, nil
+}
+
+func flagTypeError(name string, val interface{}, expected string) error {
+	return fmt.Errorf("flag %s holds %T, not a %s", name, val, expected)
+}
+
+// Returns true if flag was set on the command line or by env var. Unlike c.IsSet it also detects generic
+// flags, e.g. enums, set by env var, GenericFlag.Apply of urfave/cli v2.3.0 has a value receiver and loses that
+func isFlagSet(c *cli.Context, name string) bool {
+	if c.IsSet(name) {
+		return true
+	}
+	for _, ctx := range c.Lineage() {
+		var flags []cli.Flag
+		if ctx.Command != nil {
+			flags = append(flags, ctx.Command.Flags...)
+		}
+		if ctx.App != nil {
+			flags = append(flags, ctx.App.Flags...)
+		}
+		var generic *cli.GenericFlag
+		switch flag := findFlag(flags, name).(type) {
+		case nil:
+			continue
+		case *altsrc.GenericFlag:
+			generic = flag.GenericFlag
+		case *cli.GenericFlag:
+			generic = flag
+		default:
+			return false
+		}
+		for _, envVar := range generic.EnvVars {
+			if value, ok := os.LookupEnv(strings.TrimSpace(envVar)); ok && len(value) > 0 {
+				return true
+			}
+		}
+		return false
 	}
-	return val.(string)
+	return false
 }
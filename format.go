@@ -0,0 +1,408 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ake-persson/mapslice-json"
+	"gopkg.in/yaml.v2"
+)
+
+// Supported config file formats, jsonc is json with comments
+var configFormats = []string{"json", "jsonc", "yaml", "toml"}
+
+// Returns the config format implied by the file extension, defaults to json
+func configFormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	case ".jsonc":
+		return "jsonc"
+	}
+	return "json"
+}
+
+// Returns format if it is supported, an empty format is resolved from the file extension
+func resolveConfigFormat(format string, path string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if len(format) == 0 {
+		return configFormatOf(path), nil
+	}
+	for _, supported := range configFormats {
+		if format == supported {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported config format %s, expected one of %s", format, strings.Join(configFormats, ", "))
+}
+
+// Decodes a config document keeping the order of its top level keys where the format allows it
+func decodeConfigDocument(bts []byte, format string) (mapslice.MapSlice, error) {
+	document := mapslice.MapSlice{}
+	switch format {
+	case "yaml":
+		var ordered yaml.MapSlice
+		if err := yaml.Unmarshal(bts, &ordered); err != nil {
+			return nil, err
+		}
+		var values map[string]yamlValue
+		if err := yaml.Unmarshal(bts, &values); err != nil {
+			return nil, err
+		}
+		for _, item := range ordered {
+			key := fmt.Sprint(item.Key)
+			document = append(document, mapslice.MapItem{Key: key, Value: normalizeConfigValue(values[key].value)})
+		}
+	case "toml":
+		var values map[string]interface{}
+		if _, err := toml.Decode(string(bts), &values); err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			document = append(document, mapslice.MapItem{Key: key, Value: normalizeConfigValue(values[key])})
+		}
+	default:
+		if format == "jsonc" {
+			bts = stripJSONComments(bts)
+		}
+		if len(bytes.TrimSpace(bts)) == 0 {
+			return document, nil
+		}
+		if err := json.Unmarshal(bts, &document); err != nil {
+			return nil, err
+		}
+	}
+	return document, nil
+}
+
+// Value of a yaml document, scalars which yaml 1.1 resolves to booleans without them being written as true
+// or false, e.g. no or on, are kept as strings so string fields keep their value
+type yamlValue struct {
+	value interface{}
+}
+
+func (v *yamlValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&v.value); err != nil {
+		return err
+	}
+	switch v.value.(type) {
+	case map[interface{}]interface{}:
+		var object map[string]yamlValue
+		if err := unmarshal(&object); err != nil {
+			return err
+		}
+		values := make(map[string]interface{}, len(object))
+		for key, item := range object {
+			values[key] = item.value
+		}
+		v.value = values
+	case []interface{}:
+		var list []yamlValue
+		if err := unmarshal(&list); err != nil {
+			return err
+		}
+		values := make([]interface{}, len(list))
+		for i, item := range list {
+			values[i] = item.value
+		}
+		v.value = values
+	case bool:
+		var raw string
+		if err := unmarshal(&raw); err != nil {
+			return err
+		}
+		if _, err := strconv.ParseBool(raw); err != nil {
+			v.value = raw
+		}
+	}
+	return nil
+}
+
+// Parses a boolean as strconv.ParseBool does, or written as a yaml 1.1 boolean such as yes, no, on or off
+func parseConfigBool(raw string) (bool, error) {
+	switch raw {
+	case "y", "Y", "yes", "Yes", "YES", "on", "On", "ON":
+		return true, nil
+	case "n", "N", "no", "No", "NO", "off", "Off", "OFF":
+		return false, nil
+	}
+	return strconv.ParseBool(raw)
+}
+
+// Converts nested yaml and toml values into the types produced by encoding/json
+func normalizeConfigValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			normalized[fmt.Sprint(key)] = normalizeConfigValue(item)
+		}
+		return normalized
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			normalized[key] = normalizeConfigValue(item)
+		}
+		return normalized
+	case yaml.MapSlice:
+		normalized := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			normalized[fmt.Sprint(item.Key)] = normalizeConfigValue(item.Value)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalizeConfigValue(item)
+		}
+		return normalized
+	case []map[string]interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalizeConfigValue(item)
+		}
+		return normalized
+	}
+	return value
+}
+
+// Encodes a flat config document in given format, formats supporting comments get
+// each key documented with its help text, env vars, default and constraints
+func (b *Builder) renderConfig(document mapslice.MapSlice, format string) ([]byte, error) {
+	document = b.typeConfigDocument(document)
+	if format == "json" {
+		bts, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(bts, '\n'), nil
+	}
+	commentPrefix, indent := "# ", ""
+	if format == "jsonc" {
+		commentPrefix, indent = "// ", "  "
+	}
+	var buf bytes.Buffer
+	if format == "jsonc" {
+		buf.WriteString("{\n")
+	}
+	var group string
+	for i, item := range document {
+		key := fmt.Sprint(item.Key)
+		if field := b.configFieldByName(key); field != nil {
+			if fieldGroup := field.group(); fieldGroup != group || i == 0 {
+				if i > 0 {
+					buf.WriteString("\n")
+				}
+				if len(fieldGroup) > 0 {
+					fmt.Fprintf(&buf, "%s%s%s\n\n", indent, commentPrefix, fieldGroup)
+				}
+				group = fieldGroup
+			} else {
+				buf.WriteString("\n")
+			}
+			for _, comment := range b.configComments(field) {
+				fmt.Fprintf(&buf, "%s%s%s\n", indent, commentPrefix, comment)
+			}
+		} else if i > 0 {
+			buf.WriteString("\n")
+		}
+		value, err := renderConfigValue(item.Value, format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %s", key, err)
+		}
+		switch format {
+		case "yaml":
+			if strings.HasPrefix(value, "\n") {
+				fmt.Fprintf(&buf, "%s:%s\n", key, value) // Nested values start on the next line
+			} else {
+				fmt.Fprintf(&buf, "%s: %s\n", key, value)
+			}
+		case "toml":
+			fmt.Fprintf(&buf, "%s = %s\n", renderTomlKey(key), value)
+		default:
+			separator := ","
+			if i == len(document)-1 {
+				separator = ""
+			}
+			bts, _ := json.Marshal(key)
+			fmt.Fprintf(&buf, "%s%s: %s%s\n", indent, bts, value, separator)
+		}
+	}
+	if format == "jsonc" {
+		buf.WriteString("}\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// Returns a copy of document with strings of bool fields, e.g. yes kept from a yaml document, converted
+// into booleans, including those in nested objects and profiles
+func (b *Builder) typeConfigDocument(document mapslice.MapSlice) mapslice.MapSlice {
+	typed := make(mapslice.MapSlice, len(document))
+	for i, item := range document {
+		key := fmt.Sprint(item.Key)
+		if profiles, ok := item.Value.(map[string]interface{}); ok && key == "profiles" {
+			typedProfiles := make(map[string]interface{}, len(profiles))
+			for name, profile := range profiles {
+				typedProfiles[name] = b.typeConfigValue("", profile)
+			}
+			typed[i] = mapslice.MapItem{Key: item.Key, Value: typedProfiles}
+			continue
+		}
+		typed[i] = mapslice.MapItem{Key: item.Key, Value: b.typeConfigValue(key, item.Value)}
+	}
+	return typed
+}
+
+func (b *Builder) typeConfigValue(name string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		typed := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if len(name) > 0 {
+				typed[key] = b.typeConfigValue(name+"-"+key, item)
+			} else {
+				typed[key] = b.typeConfigValue(key, item)
+			}
+		}
+		return typed
+	case string:
+		if field := b.configFieldByName(name); field != nil && field.field.Type.Kind() == reflect.Bool {
+			if parsed, err := parseConfigBool(v); err == nil {
+				return parsed
+			}
+		}
+	}
+	return value
+}
+
+// Lines documenting a config field in dumped files
+func (b *Builder) configComments(field *configField) (comments []string) {
+	if help := field.field.Tag.Get("help"); len(help) > 0 {
+		comments = append(comments, help)
+	}
+	defaultValue := fmt.Sprint(b.configDefault(field.name))
+	if field.secret() && len(defaultValue) > 0 {
+		defaultValue = maskedValue
+	} else if len(defaultValue) == 0 {
+		defaultValue = `""`
+	}
+	comments = append(comments, fmt.Sprintf("env: %s, default: %s", strings.Join(field.envVars, ", "), defaultValue))
+	if constraints := field.constraints(); len(constraints) > 0 {
+		comments = append(comments, strings.Join(constraints, ", "))
+	}
+	return comments
+}
+
+// Encodes a single value, nested values are written inline except in yaml, where objects and lists
+// are written as indented blocks starting on the next line
+func renderConfigValue(value interface{}, format string) (string, error) {
+	if format == "json" || format == "jsonc" {
+		bts, err := json.Marshal(value)
+		return string(bts), err
+	}
+	if format == "yaml" {
+		bts, err := yaml.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		if kind := reflect.ValueOf(value).Kind(); (kind == reflect.Map || kind == reflect.Slice) && reflect.ValueOf(value).Len() > 0 {
+			return "\n  " + strings.ReplaceAll(strings.TrimSpace(string(bts)), "\n", "\n  "), nil
+		}
+		return strings.TrimSpace(string(bts)), nil
+	}
+	switch v := value.(type) {
+	case string:
+		bts, err := json.Marshal(v) // Json escaping is valid in toml basic strings
+		return string(bts), err
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var items []string
+		for _, key := range keys {
+			item, err := renderConfigValue(v[key], format)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, fmt.Sprintf("%s = %s", renderTomlKey(key), item))
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			rendered, err := renderConfigValue(item, format)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, rendered)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case nil:
+		return `""`, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// Quotes a toml key unless it only consists of bare key characters
+func renderTomlKey(key string) string {
+	for _, char := range key {
+		if !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || char == '-' || char == '_') {
+			bts, _ := json.Marshal(key)
+			return string(bts)
+		}
+	}
+	return key
+}
+
+// Removes // and /* */ comments outside of strings from a json document
+func stripJSONComments(bts []byte) []byte {
+	var out bytes.Buffer
+	inString, escaped := false, false
+	for i := 0; i < len(bts); i++ {
+		char := bts[i]
+		switch {
+		case inString:
+			out.WriteByte(char)
+			if escaped {
+				escaped = false
+			} else if char == '\\' {
+				escaped = true
+			} else if char == '"' {
+				inString = false
+			}
+		case char == '"':
+			inString = true
+			out.WriteByte(char)
+		case char == '/' && i+1 < len(bts) && bts[i+1] == '/':
+			for i < len(bts) && bts[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case char == '/' && i+1 < len(bts) && bts[i+1] == '*':
+			end := bytes.Index(bts[i+2:], []byte("*/"))
+			if end < 0 {
+				return out.Bytes()
+			}
+			i += end + 3
+		default:
+			out.WriteByte(char)
+		}
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ake-persson/mapslice-json"
+)
+
+func TestRenderConfigRoundTrip(t *testing.T) {
+	type config struct {
+		Name     string
+		Enabled  bool
+		Tags     []string
+		Database struct {
+			Host string
+			Port int
+		}
+	}
+	b := New("test", "").Config(config{})
+	b.preConfig()
+	document := mapslice.MapSlice{
+		{Key: "name", Value: "no"},
+		{Key: "enabled", Value: true},
+		{Key: "tags", Value: []interface{}{"a", "b"}},
+		{Key: "database", Value: map[string]interface{}{"host": "db", "port": 5432}},
+		{Key: "profiles", Value: map[string]interface{}{
+			"prod": map[string]interface{}{"enabled": false, "tags": []interface{}{"c"}, "database": map[string]interface{}{"port": 6543}},
+		}},
+	}
+	expect := func(t *testing.T, layer configLayer, values map[string]string) {
+		t.Helper()
+		for key, expected := range values {
+			if got := fmt.Sprint(layerValues(layer[key])); got != expected {
+				t.Errorf("expected %s to be %s, got %s", key, expected, got)
+			}
+		}
+	}
+	for _, format := range configFormats {
+		t.Run(format, func(t *testing.T) {
+			bts, err := b.renderConfig(document, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := decodeConfigDocument(bts, format); err != nil {
+				t.Fatalf("failed to decode rendered document: %s\n%s", err, bts)
+			}
+			layer, err := parseConfigLayer(bts, format)
+			if err != nil {
+				t.Fatalf("failed to parse rendered document: %s\n%s", err, bts)
+			}
+			expect(t, layer, map[string]string{"name": "[no]", "enabled": "[true]", "tags": "[a b]", "database-host": "[db]", "database-port": "[5432]"})
+			if problems := b.configLayerProblems(layer, ""); len(problems) > 0 {
+				t.Fatalf("unexpected problems %v\n%s", problems, bts)
+			}
+			prod, err := layer.withProfile("prod")
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect(t, prod, map[string]string{"name": "[no]", "enabled": "[false]", "tags": "[c]", "database-host": "[db]", "database-port": "[6543]"})
+		})
+	}
+}
+
+func TestDecodeYAMLBooleanWords(t *testing.T) {
+	type config struct {
+		Name    string
+		Enabled bool
+	}
+	b := New("test", "").Config(config{})
+	b.preConfig()
+	document, err := decodeConfigDocument([]byte("name: no\nenabled: yes\nquoted: \"on\"\nplain: true\n"), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mapslice.MapSlice{{Key: "name", Value: "no"}, {Key: "enabled", Value: "yes"}, {Key: "quoted", Value: "on"}, {Key: "plain", Value: true}}
+	if fmt.Sprintf("%#v", document) != fmt.Sprintf("%#v", expected) {
+		t.Fatalf("expected %v, got %v", expected, document)
+	}
+	bts, err := b.renderConfig(document, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := decodeConfigDocument(bts, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered[0].Value != "no" || rendered[1].Value != true {
+		t.Fatalf("expected name to stay a string and enabled to become a boolean, got %v\n%s", rendered, bts)
+	}
+}
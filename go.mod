@@ -3,15 +3,16 @@ module github.com/tiinusen/cli
 go 1.17
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/ake-persson/mapslice-json v0.0.0-20210720081907-22c8edf57807
 	github.com/urfave/cli/v2 v2.3.0
 	golang.org/x/sys v0.0.0-20211013075003-97ac67df715c
+	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211
+	gopkg.in/yaml.v2 v2.2.3
 )
 
 require (
-	github.com/BurntSushi/toml v0.3.1 // indirect
 	github.com/cpuguy83/go-md2man/v2 v2.0.0-20190314233015-f79a8a8ca69d // indirect
 	github.com/russross/blackfriday/v2 v2.0.1 // indirect
 	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
-	gopkg.in/yaml.v2 v2.2.3 // indirect
 )
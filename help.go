@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Heading of the group holding flags built in for handling the config file
+const configFileFlagsHeading = "CONFIG FILE OPTIONS"
+
+// Replaces the flat global options list of the app help with flags grouped under headings,
+// config fields are grouped by their category tag or the name of their nested struct
+func (b *Builder) groupFlagHelp() {
+	start := strings.Index(cli.AppHelpTemplate, "{{if .VisibleFlags}}\n\nGLOBAL OPTIONS:")
+	end := strings.Index(cli.AppHelpTemplate, "{{if .Copyright}}")
+	if start < 0 || end < start {
+		return
+	}
+
+	builtin := make(map[cli.Flag]bool)
+	for _, flag := range b.builtinFlags {
+		builtin[flag] = true
+	}
+	var headings []string
+	groups := make(map[string][]cli.Flag)
+	add := func(heading string, flag cli.Flag) {
+		if _, exists := groups[heading]; !exists {
+			headings = append(headings, heading)
+		}
+		groups[heading] = append(groups[heading], flag)
+	}
+	add("GLOBAL OPTIONS", nil)
+	for _, flag := range b.app.VisibleFlags() {
+		switch field := b.configFieldByName(flag.Names()[0]); {
+		case builtin[flag]:
+			add(configFileFlagsHeading, flag)
+		case field != nil && len(b.configCategory(field)) > 0:
+			add(strings.ToUpper(b.configCategory(field))+" OPTIONS", flag)
+		default:
+			add("GLOBAL OPTIONS", flag)
+		}
+	}
+	if !b.app.HideHelp {
+		add("GLOBAL OPTIONS", cli.HelpFlag)
+	}
+	if !b.app.HideVersion && len(b.app.Version) > 0 {
+		add("GLOBAL OPTIONS", cli.VersionFlag)
+	}
+
+	var sections strings.Builder
+	for _, heading := range headings {
+		var lines []string
+		for _, flag := range groups[heading] {
+			if flag != nil {
+				lines = append(lines, strings.ReplaceAll(fmt.Sprint(flag), "{{", `{{"{{"}}`))
+			}
+		}
+		if len(lines) > 0 {
+			fmt.Fprintf(&sections, "\n\n%s:\n   %s", heading, strings.Join(lines, "\n   "))
+		}
+	}
+	if len(b.constraints[nil]) > 0 {
+		fmt.Fprintf(&sections, "\n\nCONSTRAINTS:\n   %s", strings.NewReplacer("\n", "\n   ", "{{", `{{"{{"}}`).Replace(constraintHelp(b.constraints[nil])))
+	}
+	b.app.CustomAppHelpTemplate = cli.AppHelpTemplate[:start] + "{{if .VisibleFlags}}" + sections.String() + "{{end}}" + cli.AppHelpTemplate[end:]
+}
+
+// Returns the help category of a config field, the innermost category tag along its
+// struct path wins, otherwise the name of the nested struct holding it
+func (b *Builder) configCategory(field *configField) (category string) {
+	typeOfStruct := reflect.TypeOf(b.config)
+	segments := strings.Split(field.path, ".")
+	for _, segment := range segments {
+		for typeOfStruct.Kind() == reflect.Ptr {
+			typeOfStruct = typeOfStruct.Elem()
+		}
+		if typeOfStruct.Kind() != reflect.Struct {
+			break
+		}
+		structField, ok := typeOfStruct.FieldByName(segment)
+		if !ok {
+			break
+		}
+		if tag := strings.TrimSpace(structField.Tag.Get("category")); len(tag) > 0 {
+			category = tag
+		}
+		typeOfStruct = structField.Type
+	}
+	if len(category) == 0 && len(segments) > 1 {
+		category = strings.ReplaceAll(dash(strings.Join(segments[:len(segments)-1], "-")), "-", " ")
+	}
+	return category
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Implemented by the config type or its nested structs to fill derived values after parsing
+type Normalizer interface {
+	Normalize()
+}
+
+// Implemented by the config type or its nested structs to check rules spanning several fields
+type Validator interface {
+	Validate() error
+}
+
+// Invokes Normalize() and then Validate() on the parsed config, or another struct described by subject,
+// and its nested structs, nested structs are visited before the struct holding them
+func runConfigHooks(valueOfConfig reflect.Value, subject string) error {
+	walkConfigHooks(valueOfConfig, "", true, func(value reflect.Value, path string) error {
+		if normalizer, ok := value.Addr().Interface().(Normalizer); ok {
+			normalizer.Normalize()
+		}
+		return nil
+	})
+	return walkConfigHooks(valueOfConfig, "", true, func(value reflect.Value, path string) error {
+		validator, ok := value.Addr().Interface().(Validator)
+		if !ok {
+			return nil
+		}
+		if err := validator.Validate(); err != nil {
+			if len(path) == 0 {
+				return fmt.Errorf("invalid %s: %s", subject, err)
+			}
+			return fmt.Errorf("invalid %s %s: %s", subject, path, err)
+		}
+		return nil
+	})
+}
+
+// Visits addressable structs of the config, embedded structs are only descended into
+// since their methods are promoted to the struct holding them
+func walkConfigHooks(valueOfStruct reflect.Value, path string, visit bool, hook func(value reflect.Value, path string) error) error {
+	typeOfStruct := valueOfStruct.Type()
+	for i := 0; i < typeOfStruct.NumField(); i++ {
+		fieldOfField := typeOfStruct.Field(i)
+		valueOfField := valueOfStruct.Field(i)
+		if skipConfigField(fieldOfField) || fieldOfField.Type.Kind() != reflect.Struct || fieldOfField.Type == reflect.TypeOf(time.Time{}) {
+			continue
+		}
+		fieldPath := fieldOfField.Name
+		if len(path) > 0 {
+			fieldPath = path + "." + fieldPath
+		}
+		if inlineConfigField(fieldOfField) {
+			fieldPath = path
+		}
+		if err := walkConfigHooks(valueOfField, fieldPath, !inlineConfigField(fieldOfField), hook); err != nil {
+			return err
+		}
+	}
+	if !visit {
+		return nil
+	}
+	return hook(valueOfStruct, path)
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"github.com/urfave/cli/v2"
+)
+
+// Middleware wraps the callback of a command, e.g. to time it, check authorization or recover from panics,
+// and decides whether and how next is invoked
+type Middleware func(next Callback) Callback
+
+// Checks the arguments or flags of a command before its callback, inside of its middleware
+type commandCheck func(cc *cli.Context) error
+
+// AfterCallback is invoked after the callback of a command with its error, which is replaced by the
+// returned error, so returning nil swallows it
+type AfterCallback func(app *Runner, args Args, flags Flags, err error) error
+
+// Adds middleware wrapping the callback of every command, the first given is the outermost
+func (b *Builder) Use(middleware ...Middleware) *Builder {
+	b.addMiddleware(nil, middleware...)
+	return b
+}
+
+// Adds middleware wrapping the callback of the most recently specified command, or of every sub command
+// of a group, inside of the middleware added by Use() and by parent commands
+func (b *Builder) CommandUse(middleware ...Middleware) *Builder {
+	b.addMiddleware(b.currentCommand("command use"), middleware...)
+	return b
+}
+
+// Sets hook to be invoked after the callback of every command
+func (b *Builder) After(hook AfterCallback) *Builder {
+	b.addAfter(nil, hook)
+	return b
+}
+
+// Sets hook to be invoked after the callback of the most recently specified command, or of every sub
+// command of a group, before the hooks of parent commands and those set by After()
+func (b *Builder) CommandAfter(hook AfterCallback) *Builder {
+	b.addAfter(b.currentCommand("command after"), hook)
+	return b
+}
+
+func (b *Builder) addMiddleware(command *cli.Command, middleware ...Middleware) {
+	if b.middleware == nil {
+		b.middleware = make(map[*cli.Command][]Middleware)
+	}
+	b.middleware[command] = append(b.middleware[command], middleware...)
+}
+
+func (b *Builder) addCheck(command *cli.Command, check commandCheck) {
+	if b.checks == nil {
+		b.checks = make(map[*cli.Command][]commandCheck)
+	}
+	b.checks[command] = append(b.checks[command], check)
+}
+
+func (b *Builder) addAfter(command *cli.Command, hook AfterCallback) {
+	if b.after == nil {
+		b.after = make(map[*cli.Command][]AfterCallback)
+	}
+	b.after[command] = append(b.after[command], hook)
+}
+
+// Invokes callback of command wrapped by the middleware of the command, its parents and the global
+// middleware, then the after hooks from the command up to the global ones. The checks of the command
+// are invoked before the callback, so their errors pass through middleware and after hooks as well
+func (b *Builder) invoke(cc *cli.Context, command *cli.Command, callback Callback) error {
+	if checks := b.checks[command]; len(checks) > 0 {
+		next := callback
+		callback = func(app *Runner, args Args, flags Flags) error {
+			for _, check := range checks {
+				if err := check(cc); err != nil {
+					return err
+				}
+			}
+			return next(app, args, flags)
+		}
+	}
+	lineage := append([]*cli.Command{nil}, commandAncestors(b.app.Commands, command)...)
+	var middleware []Middleware
+	for _, c := range lineage {
+		middleware = append(middleware, b.middleware[c]...)
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		callback = middleware[i](callback)
+	}
+	parsedArgs, parsedFlags := Args(cc.Args().Slice()), mergedFlags(cc)
+	err := callback(b.runner, parsedArgs, parsedFlags)
+	for i := len(lineage) - 1; i >= 0; i-- {
+		for _, hook := range b.after[lineage[i]] {
+			err = hook(b.runner, parsedArgs, parsedFlags, err)
+		}
+	}
+	return err
+}
+
+// Returns the commands from the top level down to command, nil if command is not among commands
+func commandAncestors(commands []*cli.Command, command *cli.Command) []*cli.Command {
+	for _, c := range commands {
+		if c == command {
+			return []*cli.Command{c}
+		}
+		if ancestors := commandAncestors(c.Subcommands, command); ancestors != nil {
+			return append([]*cli.Command{c}, ancestors...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+var runnerType = reflect.TypeOf(&Runner{})
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// CommandWith specifies a command, by name or space separated path like Command(), whose flags and
+// arguments are bound into opts, a pointer to a struct scanned with the same tag rules as the config
+// struct. Fields tagged with arg:"N" are bound to the positional argument N instead of a flag, a slice
+// field takes all remaining arguments. Current values of opts are used as defaults, and callback must
+// be a func(*cli.Runner, *T) error where T is the options struct
+func (b *Builder) CommandWith(name string, usage string, opts interface{}, callback interface{}) *Builder {
+	valueOfOpts := reflect.ValueOf(opts)
+	if valueOfOpts.Kind() != reflect.Ptr || valueOfOpts.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("options of command %s is not a pointer to a struct", name))
+	}
+	valueOfCallback := reflect.ValueOf(callback)
+	typeOfCallback := valueOfCallback.Type()
+	if typeOfCallback.Kind() != reflect.Func || typeOfCallback.NumIn() != 2 || typeOfCallback.NumOut() != 1 ||
+		typeOfCallback.In(0) != runnerType || typeOfCallback.In(1) != valueOfOpts.Type() || typeOfCallback.Out(0) != errorType {
+		panic(fmt.Sprintf("callback of command %s must be a func(*cli.Runner, %s) error", name, valueOfOpts.Type()))
+	}
+	command := b.ensureCommand(name)
+	b.command = command
+	b.command.Usage = usage
+	b.command.ArgsUsage = optionArgsUsage(valueOfOpts.Elem())
+	b.command.Action = func(cc *cli.Context) error {
+		b.runner.isMain = false
+		return b.invoke(cc, command, func(app *Runner, args Args, flags Flags) error {
+			if err := bindOptions(cc, valueOfOpts.Elem(), ""); err != nil {
+				return err
+			}
+			if err := bindOptionArgs(valueOfOpts.Elem(), args); err != nil {
+				return fmt.Errorf("%s expects %s: %s", cc.Command.HelpName, command.ArgsUsage, err)
+			}
+			if err := runConfigHooks(valueOfOpts.Elem(), name+" options"); err != nil {
+				return err
+			}
+			err, _ := valueOfCallback.Call([]reflect.Value{reflect.ValueOf(app), valueOfOpts})[0].Interface().(error)
+			return err
+		})
+	}
+	b.command.Flags = append(b.command.Flags, optionFlags(valueOfOpts.Elem(), "")...)
+	return b
+}
+
+// Describes a field of an options struct bound to a positional argument
+type optionArg struct {
+	index int
+	name  string
+	value reflect.Value
+}
+
+// Returns the fields tagged with arg:"N" ordered by their index
+func optionArgs(valueOfStruct reflect.Value) (args []optionArg) {
+	typeOfStruct := valueOfStruct.Type()
+	for i := 0; i < typeOfStruct.NumField(); i++ {
+		fieldOfField := typeOfStruct.Field(i)
+		valueOfField := valueOfStruct.Field(i)
+		if skipConfigField(fieldOfField) {
+			continue
+		} else if inlineConfigField(fieldOfField) {
+			args = append(args, optionArgs(valueOfField)...)
+			continue
+		}
+		tag, ok := fieldOfField.Tag.Lookup("arg")
+		if !ok {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimSpace(tag))
+		if err != nil || index < 0 {
+			panic(fmt.Sprintf("invalid arg tag %q of %s, expected a positional index", tag, fieldOfField.Name))
+		}
+		args = append(args, optionArg{index: index, name: dash(fieldOfField.Name), value: valueOfField})
+	}
+	sort.SliceStable(args, func(i, j int) bool {
+		return args[i].index < args[j].index
+	})
+	return args
+}
+
+// Describes positional arguments of an options struct for help, e.g. <src> [files...]
+func optionArgsUsage(valueOfStruct reflect.Value) string {
+	var usage []string
+	for _, arg := range optionArgs(valueOfStruct) {
+		if arg.value.Kind() == reflect.Slice {
+			usage = append(usage, fmt.Sprintf("[%s...]", arg.name))
+		} else {
+			usage = append(usage, fmt.Sprintf("<%s>", arg.name))
+		}
+	}
+	return strings.Join(usage, " ")
+}
+
+// Binds positional arguments into fields tagged with arg:"N", fails on missing arguments of non slice
+// fields and on arguments without a field
+func bindOptionArgs(valueOfStruct reflect.Value, args Args) error {
+	bound := 0
+	for _, arg := range optionArgs(valueOfStruct) {
+		if arg.index >= len(args) && arg.value.Kind() != reflect.Slice {
+			return fmt.Errorf("missing argument %s", arg.name)
+		} else if arg.index >= len(args) {
+			continue
+		}
+		if arg.value.Kind() == reflect.Slice {
+			arg.value.Set(reflect.ValueOf(append([]string{}, args[arg.index:]...)))
+			bound = len(args)
+			continue
+		}
+		if err := setOptionValue(arg.value, args[arg.index]); err != nil {
+			return fmt.Errorf("invalid argument %s: %s", arg.name, err)
+		}
+		if arg.index+1 > bound {
+			bound = arg.index + 1
+		}
+	}
+	if len(args) > bound {
+		return fmt.Errorf("unexpected argument %s", args[bound])
+	}
+	return nil
+}
+
+// Extracts fields of an options struct into flags, env vars are only taken from env tags
+func optionFlags(valueOfStruct reflect.Value, prefix string) (flags []cli.Flag) {
+	typeOfStruct := valueOfStruct.Type()
+	for i := 0; i < typeOfStruct.NumField(); i++ {
+		fieldOfField := typeOfStruct.Field(i)
+		valueOfField := valueOfStruct.Field(i)
+		if skipConfigField(fieldOfField) {
+			continue
+		} else if inlineConfigField(fieldOfField) {
+			flags = append(flags, optionFlags(valueOfField, prefix)...)
+			continue
+		} else if _, ok := fieldOfField.Tag.Lookup("arg"); ok {
+			continue
+		}
+		aliases := aliases(fieldOfField.Tag.Lookup("flag"))
+		flagName := fieldOfField.Name
+		if len(aliases) > 0 {
+			flagName = aliases[0]
+			aliases = aliases[1:]
+		}
+		if len(prefix) > 0 {
+			flagName = fmt.Sprintf("%s-%s", prefix, flagName)
+		}
+		flagName = dash(flagName)
+		envVars := tagEnvVars(fieldOfField.Tag.Get("env"))
+		usage := fieldOfField.Tag.Get("help")
+		hidden := hiddenConfigField(fieldOfField)
+		switch v := valueOfField.Interface().(type) {
+		case time.Time:
+			flags = append(flags, &cli.StringFlag{Name: flagName, EnvVars: envVars, Aliases: aliases, Usage: usage, Hidden: hidden})
+		case time.Duration:
+			flags = append(flags, &cli.DurationFlag{Name: flagName, EnvVars: envVars, Value: v, Aliases: aliases, Usage: usage, Hidden: hidden})
+		case ByteSize:
+			flags = append(flags, &cli.GenericFlag{Name: flagName, EnvVars: envVars, Value: &v, Aliases: aliases, Usage: usage, Hidden: hidden})
+		case Percent:
+			flags = append(flags, &cli.GenericFlag{Name: flagName, EnvVars: envVars, Value: &v, Aliases: aliases, Usage: usage, Hidden: hidden})
+		default:
+			switch valueOfField.Kind() {
+			case reflect.Struct:
+				flags = append(flags, optionFlags(valueOfField, flagName)...)
+			case reflect.Int:
+				flags = append(flags, &cli.IntFlag{Name: flagName, EnvVars: envVars, Value: int(valueOfField.Int()), Aliases: aliases, Usage: usage, Hidden: hidden})
+			case reflect.String:
+				if allowed := enumValues(fieldOfField.Tag.Get("enum")); len(allowed) > 0 {
+					value := defaultEnum(flagName, allowed, valueOfField.String())
+					flags = append(flags, &cli.GenericFlag{Name: flagName, EnvVars: envVars, Value: value, Aliases: aliases, Usage: strings.TrimSpace(usage + " " + value.usage()), Hidden: hidden})
+					continue
+				}
+				flags = append(flags, &cli.StringFlag{Name: flagName, EnvVars: envVars, Value: valueOfField.String(), Aliases: aliases, Usage: usage, Hidden: hidden})
+			case reflect.Bool:
+				flags = append(flags, &cli.BoolFlag{Name: flagName, EnvVars: envVars, Value: valueOfField.Bool(), Aliases: aliases, Usage: usage, Hidden: hidden})
+			case reflect.Slice:
+				flags = append(flags, &cli.StringSliceFlag{Name: flagName, EnvVars: envVars, Aliases: aliases, Usage: usage, Hidden: hidden})
+			}
+		}
+	}
+	return flags
+}
+
+// Binds flags into the fields of an options struct, fields keep their default when a flag is not set
+func bindOptions(cc *cli.Context, valueOfStruct reflect.Value, prefix string) error {
+	typeOfStruct := valueOfStruct.Type()
+	for i := 0; i < typeOfStruct.NumField(); i++ {
+		fieldOfField := typeOfStruct.Field(i)
+		valueOfField := valueOfStruct.Field(i)
+		if skipConfigField(fieldOfField) {
+			continue
+		} else if inlineConfigField(fieldOfField) {
+			if err := bindOptions(cc, valueOfField, prefix); err != nil {
+				return err
+			}
+			continue
+		} else if _, ok := fieldOfField.Tag.Lookup("arg"); ok {
+			continue
+		}
+		aliases := aliases(fieldOfField.Tag.Lookup("flag"))
+		flagName := fieldOfField.Name
+		if len(aliases) > 0 {
+			flagName = aliases[0]
+		}
+		if len(prefix) > 0 {
+			flagName = fmt.Sprintf("%s-%s", prefix, flagName)
+		}
+		flagName = dash(flagName)
+		if valueOfField.Kind() == reflect.Struct && valueOfField.Type() != reflect.TypeOf(time.Time{}) {
+			if err := bindOptions(cc, valueOfField, flagName); err != nil {
+				return err
+			}
+			continue
+		}
+		if !isFlagSet(cc, flagName) {
+			continue
+		}
+		switch valueOfField.Kind() {
+		case reflect.Bool:
+			valueOfField.SetBool(cc.Bool(flagName))
+		case reflect.Slice:
+			var values []string
+			for _, item := range cc.StringSlice(flagName) {
+				for _, value := range strings.Split(item, ",") {
+					values = append(values, strings.TrimSpace(value))
+				}
+			}
+			valueOfField.Set(reflect.ValueOf(values))
+		default:
+			if err := setOptionValue(valueOfField, cc.String(flagName)); err != nil {
+				return fmt.Errorf("invalid value for --%s: %s", flagName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Parses raw into a field of one of the types supported by config and options structs
+func setOptionValue(valueOfField reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	var value interface{}
+	var err error
+	switch valueOfField.Interface().(type) {
+	case time.Time:
+		value, err = parseTimeValue(raw)
+	case time.Duration:
+		value, err = time.ParseDuration(raw)
+	case ByteSize:
+		value, err = ParseByteSize(raw)
+	case Percent:
+		value, err = ParsePercent(raw)
+	default:
+		switch valueOfField.Kind() {
+		case reflect.Int:
+			var v int
+			v, err = strconv.Atoi(raw)
+			value = reflect.ValueOf(v).Convert(valueOfField.Type()).Interface()
+		case reflect.String:
+			value = reflect.ValueOf(raw).Convert(valueOfField.Type()).Interface()
+		case reflect.Bool:
+			var v bool
+			v, err = strconv.ParseBool(raw)
+			value = reflect.ValueOf(v).Convert(valueOfField.Type()).Interface()
+		default:
+			err = fmt.Errorf("unsupported type %s", valueOfField.Type())
+		}
+	}
+	if err != nil {
+		return err
+	}
+	valueOfField.Set(reflect.ValueOf(value))
+	return nil
+}
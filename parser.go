@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"reflect"
 	"regexp"
@@ -21,9 +22,34 @@ func (b *Builder) preConfig() {
 	if b.config == nil {
 		return
 	}
-	b.app.Flags = append(b.app.Flags, StringFlag("config-file", "To specify which configuration to be used"))
-	b.app.Flags = append(b.app.Flags, BooleanFlag("dump-config", "Dumps configuration to file"))
+	builtinStart := len(b.app.Flags)
+	b.app.Flags = append(b.app.Flags, StringFlag("config-file", "To specify which configuration to be used, - reads it from stdin"))
+	b.app.Flags = append(b.app.Flags, &cli.StringFlag{
+		Name:    "config-url",
+		Usage:   "To specify a http(s) url serving json or yaml configuration which overrides the configuration file, the last fetched copy is used when unreachable",
+		EnvVars: []string{"APP_CONFIG_URL"},
+	})
+	b.app.Flags = append(b.app.Flags, StringFlag("config-json", "To specify inline configuration in json which overrides the configuration file"))
+	b.app.Flags = append(b.app.Flags, BooleanFlag("dump-config", "Dumps configuration to file, showing a diff against an existing file"))
+	b.app.Flags = append(b.app.Flags, StringFlag("dump-target", "To specify where --dump-config writes, defaults to the configuration file, - writes to stdout"))
+	b.app.Flags = append(b.app.Flags, StringFlag("dump-format", "To specify the format of --dump-config: json, jsonc, yaml or toml, defaults to the file extension"))
+	b.app.Flags = append(b.app.Flags, BooleanFlag("force", "Allows --dump-config to overwrite an existing file"))
 	b.app.Flags = append(b.app.Flags, BooleanFlag("show-config", "Shows the loaded configuration"))
+	b.app.Flags = append(b.app.Flags, &cli.StringFlag{
+		Name:    "profile",
+		Usage:   "To specify which profile of the configuration to be used",
+		EnvVars: []string{"APP_PROFILE"},
+	})
+	b.app.Flags = append(b.app.Flags, &cli.StringFlag{
+		Name:    "config-key-file",
+		Usage:   "To specify the key file used to decrypt enc:v1: values of the configuration",
+		EnvVars: []string{"APP_CONFIG_KEY_FILE"},
+	})
+	b.app.Flags = append(b.app.Flags, &cli.StringSliceFlag{
+		Name:  "set",
+		Usage: "To override a configuration key by key=value, e.g. --set my-struct.my-key=5",
+	})
+	b.builtinFlags = append(b.builtinFlags, b.app.Flags[builtinStart:]...)
 	valueOfConfig := reflect.ValueOf(b.config)
 	if valueOfConfig.Kind() == reflect.Ptr {
 		valueOfConfig = valueOfConfig.Elem()
@@ -31,11 +57,11 @@ func (b *Builder) preConfig() {
 	if valueOfConfig.Type().Kind() != reflect.Struct {
 		panic("config is not a non pointer struct")
 	}
-	b.preConfigRecursiveScan(valueOfConfig, "")
+	b.preConfigRecursiveScan(valueOfConfig, "", "")
 }
 
 // Extracts struct fields into flags
-func (b *Builder) preConfigRecursiveScan(valueOfStruct reflect.Value, prefix string) {
+func (b *Builder) preConfigRecursiveScan(valueOfStruct reflect.Value, prefix string, path string) {
 	if valueOfStruct.Kind() == reflect.Ptr {
 		valueOfStruct = valueOfStruct.Elem()
 	}
@@ -43,6 +69,12 @@ func (b *Builder) preConfigRecursiveScan(valueOfStruct reflect.Value, prefix str
 	for i := 0; i < typeOfStruct.NumField(); i++ {
 		fieldOfField := typeOfStruct.Field(i)
 		valueOfField := valueOfStruct.Field(i)
+		if skipConfigField(fieldOfField) {
+			continue
+		} else if inlineConfigField(fieldOfField) {
+			b.preConfigRecursiveScan(valueOfField, prefix, path)
+			continue
+		}
 		aliases := aliases(fieldOfField.Tag.Lookup("flag"))
 		flagName := fieldOfField.Name
 		if len(aliases) > 0 {
@@ -54,6 +86,10 @@ func (b *Builder) preConfigRecursiveScan(valueOfStruct reflect.Value, prefix str
 		}
 		envName := env(flagName)
 		flagName = dash(flagName)
+		fieldPath := fieldOfField.Name
+		if len(path) > 0 {
+			fieldPath = path + "." + fieldPath
+		}
 		switch valueOfField.Kind() {
 		case reflect.Struct:
 			switch valueOfField.Interface().(type) {
@@ -64,13 +100,11 @@ func (b *Builder) preConfigRecursiveScan(valueOfStruct reflect.Value, prefix str
 					Value:   valueOfField.String(),
 					Aliases: aliases,
 					Usage:   fieldOfField.Tag.Get("help"),
+					Hidden:  hiddenConfigField(fieldOfField),
 				}))
-				b.configStructure = append(b.configStructure, mapslice.MapItem{
-					Key:   flagName,
-					Value: valueOfField.String(),
-				})
+				b.addConfigItem(flagName, valueOfField.String(), fieldOfField, fieldPath)
 			default:
-				b.preConfigRecursiveScan(valueOfField, flagName)
+				b.preConfigRecursiveScan(valueOfField, flagName, fieldPath)
 			}
 		case reflect.Int:
 			b.app.Flags = append(b.app.Flags, altsrc.NewIntFlag(&cli.IntFlag{
@@ -79,23 +113,31 @@ func (b *Builder) preConfigRecursiveScan(valueOfStruct reflect.Value, prefix str
 				Value:   int(valueOfField.Int()),
 				Aliases: aliases,
 				Usage:   fieldOfField.Tag.Get("help"),
+				Hidden:  hiddenConfigField(fieldOfField),
 			}))
-			b.configStructure = append(b.configStructure, mapslice.MapItem{
-				Key:   flagName,
-				Value: int(valueOfField.Int()),
-			})
+			b.addConfigItem(flagName, int(valueOfField.Int()), fieldOfField, fieldPath)
 		case reflect.String:
-			b.app.Flags = append(b.app.Flags, altsrc.NewStringFlag(&cli.StringFlag{
-				Name:    flagName,
-				EnvVars: envVars(envName, fieldOfField.Tag.Get("env")),
-				Value:   valueOfField.String(),
-				Aliases: aliases,
-				Usage:   fieldOfField.Tag.Get("help"),
-			}))
-			b.configStructure = append(b.configStructure, mapslice.MapItem{
-				Key:   flagName,
-				Value: valueOfField.String(),
-			})
+			if allowed := enumValues(fieldOfField.Tag.Get("enum")); len(allowed) > 0 {
+				value := defaultEnum(flagName, allowed, valueOfField.String())
+				b.app.Flags = append(b.app.Flags, altsrc.NewGenericFlag(&cli.GenericFlag{
+					Name:    flagName,
+					EnvVars: envVars(envName, fieldOfField.Tag.Get("env")),
+					Value:   value,
+					Aliases: aliases,
+					Usage:   strings.TrimSpace(fieldOfField.Tag.Get("help") + " " + value.usage()),
+					Hidden:  hiddenConfigField(fieldOfField),
+				}))
+			} else {
+				b.app.Flags = append(b.app.Flags, altsrc.NewStringFlag(&cli.StringFlag{
+					Name:    flagName,
+					EnvVars: envVars(envName, fieldOfField.Tag.Get("env")),
+					Value:   valueOfField.String(),
+					Aliases: aliases,
+					Usage:   fieldOfField.Tag.Get("help"),
+					Hidden:  hiddenConfigField(fieldOfField),
+				}))
+			}
+			b.addConfigItem(flagName, valueOfField.String(), fieldOfField, fieldPath)
 		case reflect.Bool:
 			b.app.Flags = append(b.app.Flags, altsrc.NewBoolFlag(&cli.BoolFlag{
 				Name:    flagName,
@@ -103,24 +145,43 @@ func (b *Builder) preConfigRecursiveScan(valueOfStruct reflect.Value, prefix str
 				Value:   valueOfField.Bool(),
 				Aliases: aliases,
 				Usage:   fieldOfField.Tag.Get("help"),
+				Hidden:  hiddenConfigField(fieldOfField),
 			}))
-			b.configStructure = append(b.configStructure, mapslice.MapItem{
-				Key:   flagName,
-				Value: valueOfField.Bool(),
-			})
+			b.addConfigItem(flagName, valueOfField.Bool(), fieldOfField, fieldPath)
 		case reflect.Int64:
-			if v, ok := valueOfField.Interface().(time.Duration); ok {
+			switch v := valueOfField.Interface().(type) {
+			case time.Duration:
 				b.app.Flags = append(b.app.Flags, altsrc.NewDurationFlag(&cli.DurationFlag{
 					Name:    flagName,
 					EnvVars: envVars(envName, fieldOfField.Tag.Get("env")),
 					Value:   v,
 					Aliases: aliases,
 					Usage:   fieldOfField.Tag.Get("help"),
+					Hidden:  hiddenConfigField(fieldOfField),
 				}))
-				b.configStructure = append(b.configStructure, mapslice.MapItem{
-					Key:   flagName,
-					Value: v.String(),
-				})
+				b.addConfigItem(flagName, v.String(), fieldOfField, fieldPath)
+			case ByteSize:
+				b.app.Flags = append(b.app.Flags, altsrc.NewGenericFlag(&cli.GenericFlag{
+					Name:    flagName,
+					EnvVars: envVars(envName, fieldOfField.Tag.Get("env")),
+					Value:   &v,
+					Aliases: aliases,
+					Usage:   fieldOfField.Tag.Get("help"),
+					Hidden:  hiddenConfigField(fieldOfField),
+				}))
+				b.addConfigItem(flagName, v.String(), fieldOfField, fieldPath)
+			}
+		case reflect.Float64:
+			if v, ok := valueOfField.Interface().(Percent); ok {
+				b.app.Flags = append(b.app.Flags, altsrc.NewGenericFlag(&cli.GenericFlag{
+					Name:    flagName,
+					EnvVars: envVars(envName, fieldOfField.Tag.Get("env")),
+					Value:   &v,
+					Aliases: aliases,
+					Usage:   fieldOfField.Tag.Get("help"),
+					Hidden:  hiddenConfigField(fieldOfField),
+				}))
+				b.addConfigItem(flagName, v.String(), fieldOfField, fieldPath)
 			}
 		case reflect.Slice:
 			b.app.Flags = append(b.app.Flags, altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
@@ -128,19 +189,17 @@ func (b *Builder) preConfigRecursiveScan(valueOfStruct reflect.Value, prefix str
 				EnvVars: envVars(envName, fieldOfField.Tag.Get("env")),
 				Aliases: aliases,
 				Usage:   fieldOfField.Tag.Get("help"),
+				Hidden:  hiddenConfigField(fieldOfField),
 			}))
-			b.configStructure = append(b.configStructure, mapslice.MapItem{
-				Key:   flagName,
-				Value: []string{},
-			})
+			b.addConfigItem(flagName, []string{}, fieldOfField, fieldPath)
 		}
 	}
 }
 
 // Invoked after normal cli parsing, parses flags into struct if available
-func (b *Builder) postConfig(c *cli.Context) {
+func (b *Builder) postConfig(c *cli.Context) error {
 	if b.config == nil {
-		return
+		return nil
 	}
 	valueOfConfig := reflect.ValueOf(b.config)
 	if valueOfConfig.Type().Kind() != reflect.Struct {
@@ -155,29 +214,135 @@ func (b *Builder) postConfig(c *cli.Context) {
 	}
 
 	configFile := strings.TrimSpace(c.String("config-file"))
-	if len(configFile) == 0 {
+	configFileRequired := len(configFile) > 0
+	if !configFileRequired {
 		configFile = "config.json"
 	}
 	c.Set("config-file", configFile)
-	if _, err := os.Stat(configFile); err == nil {
-		if err := altsrc.InitInputSourceWithContext(b.app.Flags, altsrc.NewJSONSourceFromFlagFunc("config-file"))(c); err != nil {
-			if len(c.String("config-file")) > 0 {
-				b.runner.Exit(err)
-			}
+	b.runner.configFile = configFile
+	layer, err := loadConfigFile(configFile, configFileRequired)
+	if err != nil {
+		return err
+	}
+	b.runner.fileLayer = layer
+	if configURL := strings.TrimSpace(c.String("config-url")); len(configURL) > 0 {
+		remoteLayer, err := fetchConfigURL(configHTTPClient, configURL, b.configCacheDir())
+		if err != nil {
+			return err
 		}
+		layer = layer.overlay(remoteLayer)
+		if profiles, ok := remoteLayer["profiles"]; ok {
+			layer["profiles"] = profiles
+		}
+	}
+	b.runner.profile = strings.TrimSpace(c.String("profile"))
+	if layer == nil && len(b.runner.profile) > 0 {
+		return fmt.Errorf("profile %s requires a config file, %s does not exist", b.runner.profile, configFile)
+	}
+	if layer, err = layer.withProfile(b.runner.profile); err != nil {
+		return err
+	}
+	if configJSON := strings.TrimSpace(c.String("config-json")); len(configJSON) > 0 {
+		inlineLayer, err := parseConfigLayer([]byte(configJSON), "json")
+		if err != nil {
+			return fmt.Errorf("failed to parse --config-json: %s", err)
+		}
+		layer = layer.overlay(inlineLayer)
+	}
+	b.runner.configKeyFile = strings.TrimSpace(c.String("config-key-file"))
+	b.runner.encrypted = layer.encryptedValues()
+	if layer, err = layer.decrypt(b.runner.configKeyFile); err != nil {
+		return err
+	}
+	if err := b.applyConfigLayer(c, layer); err != nil {
+		return err
+	}
+	if err := b.applyConfigOverrides(c, c.StringSlice("set")); err != nil {
+		return err
 	}
 
 	b.postConfigRecursiveScan(c, valueOfConfig, "")
+	if err := runConfigHooks(valueOfConfig, "config"); err != nil {
+		return err
+	}
+	b.runner.flatConfig = b.flattenConfig(valueOfConfig)
 	b.runner.config = reflect.ValueOf(b.runner.config).Elem().Interface()
 	if c.Bool("show-config") {
-		bts, _ := json.MarshalIndent(b.runner.flatConfig, "", "  ")
+		var shownConfig mapslice.MapSlice
+		for _, item := range b.runner.flatConfig {
+			shownConfig = append(shownConfig, mapslice.MapItem{Key: item.Key, Value: b.runner.shownConfigValue(item.Key, item.Value)})
+		}
+		if len(b.runner.profile) > 0 {
+			shownConfig = append(mapslice.MapSlice{{Key: "profile", Value: b.runner.profile}}, shownConfig...)
+		}
+		bts, _ := json.MarshalIndent(shownConfig, "", "  ")
 		fmt.Println(string(bts))
 		os.Exit(0)
 	} else if c.Bool("dump-config") {
-		bts, _ := json.MarshalIndent(b.runner.flatConfig, "", "  ")
-		ioutil.WriteFile(configFile, bts, 0644)
+		if err := b.dumpConfig(c, configFile); err != nil {
+			return err
+		}
 		os.Exit(0)
 	}
+	return nil
+}
+
+// Writes the loaded configuration to --dump-target, refusing to overwrite a different file without --force
+func (b *Builder) dumpConfig(c *cli.Context, configFile string) error {
+	target := strings.TrimSpace(c.String("dump-target"))
+	if len(target) == 0 {
+		if configFile == "-" {
+			return fmt.Errorf("--dump-config cannot write to stdin, specify a file with --dump-target")
+		}
+		target = configFile
+	}
+	format, err := resolveConfigFormat(c.String("dump-format"), target)
+	if err != nil {
+		return err
+	}
+	bts, err := b.renderConfig(b.dumpDocument(), format)
+	if err != nil {
+		return err
+	}
+	if target == "-" {
+		_, err := os.Stdout.Write(bts)
+		return err
+	}
+	existing, err := ioutil.ReadFile(target)
+	if err == nil {
+		diff := unifiedDiff(target, target+" (dumped)", string(existing), string(bts))
+		if len(diff) == 0 {
+			log.Printf("%s is already up to date", target)
+			return nil
+		}
+		fmt.Fprint(os.Stderr, diff)
+		if !c.Bool("force") {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", target)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := writeFileAtomic(target, bts, false); err != nil {
+		return err
+	}
+	log.Printf("configuration was successfully dumped to %s", target)
+	return nil
+}
+
+// Returns the document written by --dump-config, the effective configuration followed by the profiles of
+// the config file. Values decrypted from enc:v1: values are written back encrypted
+func (b *Builder) dumpDocument() (document mapslice.MapSlice) {
+	for _, item := range b.runner.flatConfig {
+		value := item.Value
+		if encrypted, ok := b.runner.encrypted[fmt.Sprint(item.Key)]; ok {
+			value = encrypted
+		}
+		document = append(document, mapslice.MapItem{Key: item.Key, Value: value})
+	}
+	if profiles, ok := b.runner.fileLayer["profiles"]; ok {
+		document = append(document, mapslice.MapItem{Key: "profiles", Value: profiles})
+	}
+	return document
 }
 
 // Extracts flags into config structure
@@ -189,6 +354,12 @@ func (b *Builder) postConfigRecursiveScan(c *cli.Context, valueOfStruct reflect.
 	for i := 0; i < typeOfStruct.NumField(); i++ {
 		fieldOfField := typeOfStruct.Field(i)
 		valueOfField := valueOfStruct.Field(i)
+		if skipConfigField(fieldOfField) {
+			continue
+		} else if inlineConfigField(fieldOfField) {
+			b.postConfigRecursiveScan(c, valueOfField, prefix)
+			continue
+		}
 		aliases := aliases(fieldOfField.Tag.Lookup("flag"))
 		flagName := fieldOfField.Name
 		if len(aliases) > 0 {
@@ -202,49 +373,34 @@ func (b *Builder) postConfigRecursiveScan(c *cli.Context, valueOfStruct reflect.
 		case reflect.Struct:
 			switch valueOfField.Interface().(type) {
 			case time.Time:
-				v := strings.TrimSpace(c.String(flagName))
-				b.runner.flatConfig = append(b.runner.flatConfig, mapslice.MapItem{
-					Key:   flagName,
-					Value: c.String(flagName),
-				})
-				switch {
-				case dateTimeRegexp.MatchString(v):
-					valueOfField.Set(reflect.ValueOf(parseTime(dateTimeFormat, v)))
-				case dateRegexp.MatchString(v):
-					valueOfField.Set(reflect.ValueOf(parseTime(dateFormat, v)))
-				case timeRegexp.MatchString(v):
-					valueOfField.Set(reflect.ValueOf(parseTime(dateTimeFormat, fmt.Sprintf("%s %s", time.Now().Format(dateFormat), v))))
-				default:
+				v, err := parseTimeValue(strings.TrimSpace(c.String(flagName)))
+				if err != nil {
 					os.Exit(1)
 				}
+				valueOfField.Set(reflect.ValueOf(v))
 			default:
 				b.postConfigRecursiveScan(c, valueOfField, flagName)
 			}
 		case reflect.Int:
 			valueOfField.SetInt(int64(c.Int(flagName)))
-			b.runner.flatConfig = append(b.runner.flatConfig, mapslice.MapItem{
-				Key:   flagName,
-				Value: c.Int(flagName),
-			})
 		case reflect.String:
 			valueOfField.SetString(c.String(flagName))
-			b.runner.flatConfig = append(b.runner.flatConfig, mapslice.MapItem{
-				Key:   flagName,
-				Value: c.String(flagName),
-			})
 		case reflect.Bool:
 			valueOfField.SetBool(c.Bool(flagName))
-			b.runner.flatConfig = append(b.runner.flatConfig, mapslice.MapItem{
-				Key:   flagName,
-				Value: c.Bool(flagName),
-			})
 		case reflect.Int64:
-			if _, ok := valueOfField.Interface().(time.Duration); ok {
+			switch valueOfField.Interface().(type) {
+			case time.Duration:
 				valueOfField.Set(reflect.ValueOf(c.Duration(flagName)))
-				b.runner.flatConfig = append(b.runner.flatConfig, mapslice.MapItem{
-					Key:   flagName,
-					Value: c.Duration(flagName).String(),
-				})
+			case ByteSize:
+				if v, ok := c.Generic(flagName).(*ByteSize); ok {
+					valueOfField.Set(reflect.ValueOf(*v))
+				}
+			}
+		case reflect.Float64:
+			if _, ok := valueOfField.Interface().(Percent); ok {
+				if v, ok := c.Generic(flagName).(*Percent); ok {
+					valueOfField.Set(reflect.ValueOf(*v))
+				}
 			}
 		case reflect.Slice:
 			var values []string
@@ -254,14 +410,36 @@ func (b *Builder) postConfigRecursiveScan(c *cli.Context, valueOfStruct reflect.
 				}
 			}
 			valueOfField.Set(reflect.ValueOf(values))
-			b.runner.flatConfig = append(b.runner.flatConfig, mapslice.MapItem{
-				Key:   flagName,
-				Value: strings.Join(values, ","),
-			})
 		}
 	}
 }
 
+// Collects the values of the config struct by flag name as shown and compared by the config commands
+func (b *Builder) flattenConfig(valueOfConfig reflect.Value) (flatConfig mapslice.MapSlice) {
+	for _, field := range b.configFields {
+		valueOfField := valueOfConfig
+		for _, name := range strings.Split(field.path, ".") {
+			valueOfField = valueOfField.FieldByName(name)
+		}
+		var value interface{}
+		switch v := valueOfField.Interface().(type) {
+		case time.Time:
+			value = v.Format(dateTimeFormat)
+			if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 {
+				value = v.Format(dateFormat)
+			}
+		case time.Duration, ByteSize, Percent:
+			value = fmt.Sprint(v)
+		case []string:
+			value = strings.Join(v, ",")
+		default:
+			value = v
+		}
+		flatConfig = append(flatConfig, mapslice.MapItem{Key: field.name, Value: value})
+	}
+	return flatConfig
+}
+
 var dateTimeRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}$`)
 var dateTimeFormat = "2006-01-02 15:04:05"
 var dateRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
@@ -273,6 +451,19 @@ func parseTime(layout string, timeString string) time.Time {
 	return t
 }
 
+// Parses a time given as a date and time, a date or a time of today
+func parseTimeValue(raw string) (time.Time, error) {
+	switch {
+	case dateTimeRegexp.MatchString(raw):
+		return parseTime(dateTimeFormat, raw), nil
+	case dateRegexp.MatchString(raw):
+		return parseTime(dateFormat, raw), nil
+	case timeRegexp.MatchString(raw):
+		return parseTime(dateTimeFormat, fmt.Sprintf("%s %s", time.Now().Format(dateFormat), raw)), nil
+	}
+	return time.Time{}, fmt.Errorf("expected a time as %s, %s or %s", dateTimeFormat, dateFormat, "15:04:05")
+}
+
 func dash(name string) string {
 	parts := strings.Split(name, "-")
 	var dashedName string
@@ -318,11 +509,14 @@ func aliases(val string, ok bool) (list []string) {
 }
 
 func envVars(keyUnderscored string, optionalEnv string) (list []string) {
-	keyUnderscored = strings.ToUpper(keyUnderscored)
-	list = append(list, keyUnderscored)
-	if len(optionalEnv) > 0 {
-		for _, val := range strings.Split(optionalEnv, ",") {
-			list = append(list, strings.ToUpper(strings.Trim(val, " \t")))
+	return append([]string{strings.ToUpper(keyUnderscored)}, tagEnvVars(optionalEnv)...)
+}
+
+// Returns the env vars listed by an env tag, e.g. env:"port,http_port"
+func tagEnvVars(optionalEnv string) (list []string) {
+	for _, val := range strings.Split(optionalEnv, ",") {
+		if val = strings.Trim(val, " \t"); len(val) > 0 {
+			list = append(list, strings.ToUpper(val))
 		}
 	}
 	return list
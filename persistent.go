@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// PersistentFlags specifies flags of command which are inherited by all of its sub commands, so they may
+// be given before or after the sub command name, an empty command makes them global flags inherited by
+// every command. The command is a name or space separated path and created if missing
+func (b *Builder) PersistentFlags(command string, flags ...cli.Flag) *Builder {
+	if b.persistent == nil {
+		b.persistent = make(map[string][]cli.Flag)
+	}
+	command = strings.Join(strings.Fields(command), " ")
+	if len(command) == 0 {
+		b.app.Flags = append(b.app.Flags, flags...)
+	} else {
+		selectedCommand := b.ensureCommand(command)
+		selectedCommand.Flags = append(selectedCommand.Flags, flags...)
+	}
+	b.persistent[command] = append(b.persistent[command], flags...)
+	return b
+}
+
+// Adds persistent flags to the commands inheriting them, unless a command defines a flag by the same name
+func (b *Builder) inheritPersistentFlags() {
+	var inherit func(commands []*cli.Command, path string, inherited []cli.Flag)
+	inherit = func(commands []*cli.Command, path string, inherited []cli.Flag) {
+		for _, command := range commands {
+			commandPath := strings.TrimSpace(path + " " + command.Name)
+			defined := make(map[string]bool)
+			for _, flag := range command.Flags {
+				for _, name := range flag.Names() {
+					defined[name] = true
+				}
+			}
+			for _, flag := range inherited {
+				if !defined[flag.Names()[0]] {
+					command.Flags = append(command.Flags, flag)
+				}
+			}
+			var own []cli.Flag
+			if len(command.Subcommands) > 0 {
+				own = b.persistent[commandPath]
+			}
+			inherit(command.Subcommands, commandPath, append(append([]cli.Flag{}, inherited...), own...))
+		}
+	}
+	inherit(b.app.Commands, "", b.persistent[""])
+}
+
+// Returns the flags set for a command merged with those set for its parent commands and globally,
+// values closest to the command shadow the others
+func mergedFlags(cc *cli.Context) Flags {
+	parsedFlags := make(Flags)
+	lineage := cc.Lineage()
+	for i := len(lineage) - 1; i >= 0; i-- {
+		if lineage[i].App == nil {
+			continue // Context wrapping the context.Context given to the app, holds no flags
+		}
+		for _, flagName := range lineage[i].LocalFlagNames() {
+			parsedFlags[flagName] = lineage[i].Value(flagName)
+		}
+	}
+	return parsedFlags
+}
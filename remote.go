@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Client used to fetch --config-url
+var configHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Describes the cached copy of a remote config
+type remoteConfigMeta struct {
+	URL    string `json:"url"`
+	ETag   string `json:"etag"`
+	Format string `json:"format"`
+}
+
+// Fetches a json or yaml config over http(s), the last good copy is cached in cacheDir
+// and used when the server is unreachable or reports the config as not modified
+func fetchConfigURL(client *http.Client, rawURL string, cacheDir string) (configLayer, error) {
+	sum := sha256.Sum256([]byte(rawURL))
+	cachePath := filepath.Join(cacheDir, "config-url-"+hex.EncodeToString(sum[:8]))
+	var meta remoteConfigMeta
+	cached, cacheErr := ioutil.ReadFile(cachePath)
+	if cacheErr == nil {
+		metaBts, err := ioutil.ReadFile(cachePath + ".meta")
+		if err == nil {
+			err = json.Unmarshal(metaBts, &meta)
+		}
+		if err != nil || meta.URL != rawURL {
+			cached, cacheErr, meta = nil, fmt.Errorf("cache is incomplete"), remoteConfigMeta{}
+		}
+	}
+	fromCache := func(reason error) (configLayer, error) {
+		if cacheErr != nil {
+			return nil, fmt.Errorf("failed to fetch config from %s: %s", rawURL, reason)
+		}
+		if reason != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to fetch config from %s, using cached copy: %s\n", rawURL, reason)
+		}
+		layer, err := parseConfigLayer(cached, meta.Format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cached config of %s: %s", rawURL, err)
+		}
+		return layer, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config url %s: %s", rawURL, err)
+	}
+	req.Header.Set("Accept", "application/json, application/yaml;q=0.9")
+	if len(meta.ETag) > 0 {
+		req.Header.Set("If-None-Match", meta.ETag)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fromCache(err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		if cacheErr != nil {
+			return nil, fmt.Errorf("failed to fetch config from %s: server responded not modified without a cached copy", rawURL)
+		}
+		return fromCache(nil)
+	case resp.StatusCode >= 500:
+		return fromCache(fmt.Errorf("server responded %s", resp.Status))
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to fetch config from %s: server responded %s", rawURL, resp.Status)
+	}
+	bts, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fromCache(err)
+	}
+	format := remoteConfigFormat(rawURL, resp.Header.Get("Content-Type"))
+	layer, err := parseConfigLayer(bts, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config from %s: %s", rawURL, err)
+	}
+	meta = remoteConfigMeta{URL: rawURL, ETag: resp.Header.Get("ETag"), Format: format}
+	if err := writeRemoteConfigCache(cachePath, bts, meta); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to cache config of %s: %s\n", rawURL, err)
+	}
+	return layer, nil
+}
+
+func writeRemoteConfigCache(cachePath string, bts []byte, meta remoteConfigMeta) error {
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0700); err != nil {
+		return err
+	}
+	metaBts, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomicMode(cachePath, bts, 0600); err != nil {
+		return err
+	}
+	return writeFileAtomicMode(cachePath+".meta", metaBts, 0600)
+}
+
+// Resolves the format of a remote config from its content type, falling back to the url path
+func remoteConfigFormat(rawURL string, contentType string) string {
+	if strings.Contains(contentType, "yaml") {
+		return "yaml"
+	} else if strings.Contains(contentType, "json") {
+		return "json"
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		return configFormatOf(u.Path)
+	}
+	return "json"
+}
+
+// Directory holding cached copies of remote configs
+func (b *Builder) configCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, b.app.Name)
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchConfigURL(t *testing.T) {
+	var requests, notModified int
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"my-int-var": 5, "my-inner-struct": {"my-inner-string": "remote"}}`)
+	}))
+	defer server.Close()
+	rawURL := server.URL + "/config.json"
+	cacheDir := t.TempDir()
+
+	expect := func(step string, layer configLayer, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", step, err)
+		}
+		if layer["my-int-var"] != json.Number("5") || layer["my-inner-struct-my-inner-string"] != "remote" {
+			t.Fatalf("%s: unexpected layer %v", step, layer)
+		}
+	}
+
+	layer, err := fetchConfigURL(server.Client(), rawURL, cacheDir)
+	expect("first fetch", layer, err)
+	cached, _ := filepath.Glob(filepath.Join(cacheDir, "config-url-*"))
+	if len(cached) != 2 {
+		t.Fatalf("expected the cached copy and its meta file, got %v", cached)
+	}
+	for _, path := range cached {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		} else if info.Mode().Perm() != 0600 {
+			t.Fatalf("expected %s to be only readable by its owner, got %v", path, info.Mode().Perm())
+		}
+	}
+
+	layer, err = fetchConfigURL(server.Client(), rawURL, cacheDir)
+	expect("not modified", layer, err)
+	if notModified != 1 {
+		t.Fatalf("expected the cached etag to be sent, got %d not modified responses", notModified)
+	}
+
+	failing = true
+	layer, err = fetchConfigURL(server.Client(), rawURL, cacheDir)
+	expect("server error", layer, err)
+
+	server.Close()
+	layer, err = fetchConfigURL(server.Client(), rawURL, cacheDir)
+	expect("unreachable", layer, err)
+	if requests != 3 {
+		t.Fatalf("expected 3 requests to reach the server, got %d", requests)
+	}
+
+	if _, err := fetchConfigURL(server.Client(), rawURL, t.TempDir()); err == nil {
+		t.Fatalf("expected an error when unreachable without a cached copy")
+	}
+}
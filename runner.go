@@ -10,14 +10,19 @@ import (
 
 // Must be created by Run(), handles the running cli application
 type Runner struct {
-	builder    *Builder
-	ctx        context.Context
-	cancelFunc context.CancelFunc
-	flags      Flags
-	args       Args
-	isMain     bool
-	config     interface{}
-	flatConfig mapslice.MapSlice
+	builder       *Builder
+	ctx           context.Context
+	cancelFunc    context.CancelFunc
+	flags         Flags
+	args          Args
+	isMain        bool
+	config        interface{}
+	flatConfig    mapslice.MapSlice
+	fileLayer     configLayer       // Values of the config file as written, before decryption and overrides
+	encrypted     map[string]string // Values of keys decrypted from enc:v1: values, these are masked when shown
+	profile       string
+	configFile    string
+	configKeyFile string
 }
 
 // Application context
@@ -77,3 +82,16 @@ func (r *Runner) Args() Args {
 func (r *Runner) Config() interface{} {
 	return r.config
 }
+
+// Returns the name of the config profile in use, empty if none was selected
+func (r *Runner) Profile() string {
+	return r.profile
+}
+
+// Returns value of config key as shown to the user, masked if it was decrypted from an enc:v1: value
+func (r *Runner) shownConfigValue(key interface{}, value interface{}) interface{} {
+	if _, ok := r.encrypted[fmt.Sprint(key)]; ok && len(fmt.Sprint(value)) > 0 {
+		return maskedValue
+	}
+	return value
+}
@@ -138,6 +138,7 @@ func (b *Builder) Daemonize(serviceName string, serviceDescription string) *Buil
 		os.Exit(0)
 		return nil
 	})
+	b.command = nil // Modifiers following Daemonize() apply globally
 	return b
 }
 
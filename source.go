@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Flat set of configuration values keyed by dashed flag name
+type configLayer map[string]interface{}
+
+// Reads a config file into a layer, "-" reads from stdin. Returns a nil layer if
+// the file does not exist, unless it was required
+func loadConfigFile(path string, required bool) (configLayer, error) {
+	var bts []byte
+	var err error
+	if path == "-" {
+		if bts, err = ioutil.ReadAll(os.Stdin); err != nil {
+			return nil, fmt.Errorf("failed to read config from stdin: %s", err)
+		}
+		path = "stdin"
+	} else {
+		if _, err := os.Stat(path); os.IsNotExist(err) && !required {
+			return nil, nil
+		}
+		if bts, err = ioutil.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read config file: %s", err)
+		}
+	}
+	layer, err := parseConfigLayer(bts, configFormatOf(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config from %s: %s", path, err)
+	}
+	return layer, nil
+}
+
+// Parses a json, jsonc, yaml or toml document into a layer, nested objects are flattened into dashed keys
+func parseConfigLayer(bts []byte, format string) (configLayer, error) {
+	if format == "jsonc" {
+		bts, format = stripJSONComments(bts), "json"
+	}
+	if format != "json" {
+		ordered, err := decodeConfigDocument(bts, format)
+		if err != nil {
+			return nil, err
+		}
+		document := make(map[string]interface{}, len(ordered))
+		for _, item := range ordered {
+			document[fmt.Sprint(item.Key)] = item.Value
+		}
+		layer := make(configLayer)
+		layer.merge(document, "")
+		return layer, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(bts))
+	decoder.UseNumber()
+	var document map[string]interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected content after json document")
+	}
+	layer := make(configLayer)
+	layer.merge(document, "")
+	return layer, nil
+}
+
+// Copies values onto the layer, overwriting existing keys. A key given more than once, e.g. flat as
+// "a-b" and nested as {"a":{"b":...}}, takes the value nested deepest, see configPathLess()
+func (l configLayer) merge(values map[string]interface{}, prefix string) {
+	type entry struct {
+		key   string
+		path  []string
+		value interface{}
+	}
+	var entries []entry
+	var collect func(values map[string]interface{}, prefix string, path []string)
+	collect = func(values map[string]interface{}, prefix string, path []string) {
+		for key, value := range values {
+			keyPath := append(append([]string{}, path...), key)
+			if len(prefix) > 0 {
+				key = prefix + "-" + key
+			} else if key == "profiles" {
+				l[key] = value
+				continue
+			}
+			if nested, ok := value.(map[string]interface{}); ok {
+				collect(nested, key, keyPath)
+				continue
+			}
+			entries = append(entries, entry{key: key, path: keyPath, value: value})
+		}
+	}
+	collect(values, prefix, nil)
+	sort.Slice(entries, func(i, j int) bool {
+		return configPathLess(entries[i].path, entries[j].path)
+	})
+	for _, entry := range entries {
+		l[entry.key] = entry.value
+	}
+}
+
+// Orders paths of the same key by precedence, deeper paths come last and win, paths of equal depth are
+// ordered by their keys
+func configPathLess(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return strings.Join(a, ".") < strings.Join(b, ".")
+}
+
+// Returns a copy of the layer with the named profile overlaid on the base section
+func (l configLayer) withProfile(profile string) (configLayer, error) {
+	merged := make(configLayer)
+	for key, value := range l {
+		if key != "profiles" {
+			merged[key] = value
+		}
+	}
+	if len(profile) == 0 {
+		return merged, nil
+	}
+	profiles, _ := l["profiles"].(map[string]interface{})
+	values, ok := profiles[profile].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("profile %s is not defined in config", profile)
+	}
+	merged.merge(values, "")
+	return merged, nil
+}
+
+// Returns a copy of the layer with values of other layer copied onto it, profiles are not carried over
+func (l configLayer) overlay(other configLayer) configLayer {
+	merged := make(configLayer, len(l)+len(other))
+	for key, value := range l {
+		merged[key] = value
+	}
+	for key, value := range other {
+		if key != "profiles" {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// Sets config flags from layer, flags set by command line or env vars are left untouched
+func (b *Builder) applyConfigLayer(c *cli.Context, layer configLayer) error {
+	for _, item := range b.configStructure {
+		flagName := item.Key.(string)
+		value, exists := layer[flagName]
+		if !exists || isFlagSet(c, flagName) {
+			continue
+		}
+		for _, v := range layerValues(value) {
+			if _, ok := item.Value.(bool); ok {
+				if parsed, err := parseConfigBool(v); err == nil {
+					v = strconv.FormatBool(parsed)
+				}
+			}
+			if err := c.Set(flagName, v); err != nil {
+				return fmt.Errorf("invalid value %q for %s in config: %s", v, flagName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Converts a decoded json value into flag values
+func layerValues(value interface{}) (values []string) {
+	switch v := value.(type) {
+	case nil:
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+	default:
+		values = append(values, fmt.Sprint(v))
+	}
+	return values
+}
+
+// Applies --set key=value overrides, these take precedence over every other source
+func (b *Builder) applyConfigOverrides(c *cli.Context, overrides []string) error {
+	for _, override := range overrides {
+		parts := strings.SplitN(override, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid --set %s, expected key=value", override)
+		}
+		flagName, isSlice, ok := b.configKey(parts[0])
+		if !ok {
+			return fmt.Errorf("unknown config key %s in --set %s", strings.TrimSpace(parts[0]), override)
+		}
+		value := parts[1]
+		if isSlice {
+			value = cli.NewStringSlice(value).Serialize() // Replaces instead of appending to previous values
+		}
+		if err := c.Set(flagName, value); err != nil {
+			return fmt.Errorf("invalid value %q for %s in --set: %s", parts[1], flagName, err)
+		}
+	}
+	return nil
+}
+
+// Resolves a dotted or dashed config path into its flag name
+func (b *Builder) configKey(path string) (flagName string, isSlice bool, ok bool) {
+	flagName = dash(strings.ReplaceAll(strings.TrimSpace(path), ".", "-"))
+	for _, item := range b.configStructure {
+		if item.Key.(string) == flagName {
+			_, isSlice = item.Value.([]string)
+			return flagName, isSlice, true
+		}
+	}
+	return "", false, false
+}
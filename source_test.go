@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestConfigEnvOverridesFile(t *testing.T) {
+	type config struct {
+		Buffer ByteSize
+		Rate   Percent
+		Format string `enum:"json,table"`
+	}
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(path, []byte(`{"buffer": "1MB", "rate": "10%", "format": "json"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	load := func() config {
+		b := New("test", "").Config(config{})
+		b.runner = &Runner{builder: b, flags: make(Flags)}
+		b.preConfig()
+		b.app.Action = func(c *cli.Context) error {
+			return b.postConfig(c)
+		}
+		if err := b.app.Run([]string{"test", "--config-file", path}); err != nil {
+			t.Fatal(err)
+		}
+		return b.runner.Config().(config)
+	}
+
+	t.Run("file", func(t *testing.T) {
+		if got := load(); got.Buffer.String() != "1MB" || got.Rate.String() != "10%" || got.Format != "json" {
+			t.Fatalf("expected values of the file, got %+v", got)
+		}
+	})
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("BUFFER", "5MB")
+		t.Setenv("RATE", "50%")
+		t.Setenv("FORMAT", "table")
+		if got := load(); got.Buffer.String() != "5MB" || got.Rate.String() != "50%" || got.Format != "table" {
+			t.Fatalf("expected values of env vars, got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a config field type holding a number of bytes, parsed from values
+// like 512MB, 1.5GiB or 10k using SI (powers of 1000) or IEC (powers of 1024) units
+type ByteSize int64
+
+type byteSizeUnit struct {
+	suffix string
+	size   int64
+}
+
+// Units in the order they are tried when printing, SI first so 1000 becomes 1kB and 1024 becomes 1KiB
+var byteSizeUnits = []byteSizeUnit{
+	{"kB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12}, {"PB", 1e15}, {"EB", 1e18},
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40}, {"PiB", 1 << 50}, {"EiB", 1 << 60},
+}
+
+var byteSizeRegexp = regexp.MustCompile(`^([0-9]*\.?[0-9]+)\s*([a-zA-Z]*)$`)
+
+// Parses a byte size, the unit is case insensitive and may be shortened to its prefix, e.g. 10k or 2Gi
+func ParseByteSize(s string) (ByteSize, error) {
+	match := byteSizeRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, fmt.Errorf("invalid byte size %q, expected e.g. 512MB or 1GiB", s)
+	}
+	size := int64(1)
+	if unit := strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(match[2], "B"), "b")); len(unit) > 0 {
+		size = 0
+		for _, byteSizeUnit := range byteSizeUnits {
+			if strings.ToLower(strings.TrimSuffix(byteSizeUnit.suffix, "B")) == unit {
+				size = byteSizeUnit.size
+			}
+		}
+		if size == 0 {
+			return 0, fmt.Errorf("invalid byte size %q, unknown unit %s", s, match[2])
+		}
+	}
+	if n, err := strconv.ParseInt(match[1], 10, 64); err == nil {
+		if n > math.MaxInt64/size {
+			return 0, fmt.Errorf("byte size %q is too large", s)
+		}
+		return ByteSize(n * size), nil
+	}
+	f, err := strconv.ParseFloat(match[1], 64)
+	if err != nil || f*float64(size) >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	return ByteSize(math.Round(f * float64(size))), nil
+}
+
+// Prints the size using the unit giving the shortest exact representation
+func (s ByteSize) String() string {
+	shortest := strconv.FormatInt(int64(s), 10) + "B"
+	if s == 0 {
+		return shortest
+	}
+	for _, unit := range byteSizeUnits {
+		if int64(s)%unit.size == 0 {
+			if candidate := strconv.FormatInt(int64(s)/unit.size, 10) + unit.suffix; len(candidate) < len(shortest) {
+				shortest = candidate
+			}
+		}
+	}
+	return shortest
+}
+
+// Implements cli.Generic
+func (s *ByteSize) Set(value string) error {
+	v, err := ParseByteSize(value)
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// Implements flag.Getter
+func (s *ByteSize) Get() interface{} {
+	return *s
+}
+
+// Percent is a config field type holding a percentage, parsed from values like 75% or 12.5
+type Percent float64
+
+// Parses a percentage, the percent sign is optional
+func ParsePercent(s string) (Percent, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "%")), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid percentage %q, expected e.g. 75%%", s)
+	}
+	return Percent(v), nil
+}
+
+// Returns the percentage as a fraction, e.g. 0.75 for 75%
+func (p Percent) Fraction() float64 {
+	return float64(p) / 100
+}
+
+func (p Percent) String() string {
+	return strconv.FormatFloat(float64(p), 'f', -1, 64) + "%"
+}
+
+// Implements cli.Generic
+func (p *Percent) Set(value string) error {
+	v, err := ParsePercent(value)
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// Implements flag.Getter
+func (p *Percent) Get() interface{} {
+	return *p
+}
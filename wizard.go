@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ake-persson/mapslice-json"
+	"golang.org/x/term"
+)
+
+// Walks through every config field, asking for a value and writing the answers to the config file
+func (b *Builder) configInit(runner *Runner, flags Flags) error {
+	if runner.configFile == "-" {
+		return fmt.Errorf("cannot write configuration to stdin, specify a file with --config-file")
+	}
+	if _, err := os.Stat(runner.configFile); err == nil && !flags.Boolean("force") {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", runner.configFile)
+	}
+	var document mapslice.MapSlice
+	var err error
+	if flags.Boolean("non-interactive") || len(flags.String("answers")) > 0 {
+		document, err = b.configAnswers(flags.String("answers"))
+	} else {
+		document, err = b.configWizard(bufio.NewReader(os.Stdin), os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	return b.writeConfigDocument(runner.configFile, document)
+}
+
+// Prompts for every config field, empty answers keep the default
+func (b *Builder) configWizard(reader *bufio.Reader, out io.Writer) (document mapslice.MapSlice, err error) {
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	for _, field := range b.configFields {
+		defaultValue := fmt.Sprint(b.configDefault(field.name))
+		shownDefault := defaultValue
+		if field.secret() && len(defaultValue) > 0 {
+			shownDefault = maskedValue
+		}
+		if help := field.field.Tag.Get("help"); len(help) > 0 {
+			fmt.Fprintf(out, "\n# %s\n", help)
+		} else {
+			fmt.Fprintln(out)
+		}
+		for {
+			fmt.Fprintf(out, "%s [%s]: ", field.name, shownDefault)
+			var answer string
+			if field.secret() && interactive {
+				bts, err := term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Fprintln(out)
+				if err != nil {
+					return nil, err
+				}
+				answer = string(bts)
+			} else {
+				answer, err = reader.ReadString('\n')
+				if err == io.EOF && len(answer) == 0 {
+					return nil, fmt.Errorf("unexpected end of input at %s", field.name)
+				} else if err != nil && err != io.EOF {
+					return nil, err
+				}
+			}
+			answer = strings.TrimSpace(answer)
+			if len(answer) == 0 {
+				answer = defaultValue
+			}
+			value, err := field.parse(answer)
+			if err != nil {
+				fmt.Fprintf(out, "invalid value: %s\n", err)
+				continue
+			}
+			document = append(document, mapslice.MapItem{Key: field.name, Value: value})
+			break
+		}
+	}
+	return document, nil
+}
+
+// Takes config values from an answers file or env vars, falling back to defaults
+func (b *Builder) configAnswers(answersFile string) (document mapslice.MapSlice, err error) {
+	answers := make(configLayer)
+	if len(answersFile) > 0 {
+		bts, err := ioutil.ReadFile(answersFile)
+		if err != nil {
+			return nil, err
+		}
+		if answers, err = parseConfigLayer(bts, configFormatOf(answersFile)); err != nil {
+			return nil, fmt.Errorf("failed to parse answers file %s: %s", answersFile, err)
+		}
+	}
+	var problems []string
+	for _, field := range b.configFields {
+		answer := fmt.Sprint(b.configDefault(field.name))
+		if value, ok := answers[field.name]; ok {
+			answer = strings.Join(layerValues(value), ",")
+		} else {
+			for _, envVar := range field.envVars {
+				if value, ok := os.LookupEnv(envVar); ok {
+					answer = value
+					break
+				}
+			}
+		}
+		value, err := field.parse(answer)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		document = append(document, mapslice.MapItem{Key: field.name, Value: value})
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid answers:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return document, nil
+}
+
+// Returns the default value of a config key as registered from the config struct
+func (b *Builder) configDefault(flagName string) interface{} {
+	for _, item := range b.configStructure {
+		if item.Key == flagName {
+			if values, ok := item.Value.([]string); ok {
+				return strings.Join(values, ",")
+			}
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// Reads a single line from stdin without echoing it when stdin is a terminal
+func readSecret(prompt string) (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprint(os.Stderr, prompt)
+		bts, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return string(bts), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return "", fmt.Errorf("failed to read value: %s", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}